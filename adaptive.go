@@ -0,0 +1,90 @@
+package retry
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// AIMD is an adaptive delay that is shared by every call made through the
+// Retryers it has been applied to. Each failed attempt multiplicatively increases
+// the delay, and each successful attempt additively decreases it by a fixed step,
+// so a struggling dependency quickly gets gentler traffic from everyone
+// and gradually recovers its throughput once it starts succeeding again.
+type AIMD struct {
+	mu      sync.Mutex
+	min     time.Duration
+	max     time.Duration
+	step    time.Duration
+	factor  float64
+	current time.Duration
+}
+
+// NewAIMD creates an adaptive delay that starts at min and never exceeds max.
+// Each failure multiplies the delay by factor, which must be greater than 1,
+// and each success removes step from it.
+func NewAIMD(min, max, step time.Duration, factor float64) (*AIMD, error) {
+	if min <= 0 {
+		return nil, errors.New(`min delay must be a positive value`)
+	}
+	if max < min {
+		return nil, errors.New(`max delay must not be less than min delay`)
+	}
+	if step <= 0 {
+		return nil, errors.New(`step must be a positive value`)
+	}
+	if factor <= 1 {
+		return nil, errors.New(`factor must be greater than 1`)
+	}
+	return &AIMD{
+		min:     min,
+		max:     max,
+		step:    step,
+		factor:  factor,
+		current: min,
+	}, nil
+}
+
+// Delay returns the current delay that is applied after a failed attempt.
+func (a *AIMD) Delay() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.current
+}
+
+func (a *AIMD) record(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err != nil {
+		if next := float64(a.current) * a.factor; next < float64(a.max) {
+			a.current = time.Duration(next)
+		} else {
+			a.current = a.max
+		}
+	} else {
+		a.current -= a.step
+	}
+
+	if a.current > a.max {
+		a.current = a.max
+	}
+	if a.current < a.min {
+		a.current = a.min
+	}
+}
+
+// WithAdaptiveBackoff waits for the current delay of the shared AIMD after each
+// failed attempt, and reports the result of every attempt back to it.
+func WithAdaptiveBackoff(a *AIMD) Option {
+	return func(r *retry) error {
+		if a == nil {
+			return errors.New(`adaptive backoff must not be nil`)
+		}
+		r.delays = append(r.delays, func(_, _ int) time.Duration {
+			return a.Delay()
+		})
+		r.observers = append(r.observers, a.record)
+		return nil
+	}
+}
@@ -0,0 +1,61 @@
+package retry_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+func TestInvalidAIMD(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		min, max, step time.Duration
+		factor         float64
+		msg            string
+	}{
+		{min: 0, max: time.Second, step: time.Millisecond, factor: 2, msg: `min must be positive`},
+		{min: time.Second, max: time.Millisecond, step: time.Millisecond, factor: 2, msg: `max must not be less than min`},
+		{min: time.Millisecond, max: time.Second, step: 0, factor: 2, msg: `step must be positive`},
+		{min: time.Millisecond, max: time.Second, step: time.Millisecond, factor: 1.0, msg: `factor must be greater than 1`},
+		{min: time.Millisecond, max: time.Second, step: time.Millisecond, factor: 0.5, msg: `factor must not shrink the delay`},
+	}
+
+	for _, test := range tests {
+		a, err := retry.NewAIMD(test.min, test.max, test.step, test.factor)
+		assert.Error(t, err, test.msg)
+		assert.Nil(t, a, test.msg)
+	}
+
+	_, err := retry.New(retry.WithAdaptiveBackoff(nil))
+	assert.Error(t, err, `Must not allow a nil adaptive backoff`)
+}
+
+func TestAIMDSharedBetweenRetryers(t *testing.T) {
+	t.Parallel()
+
+	a, err := retry.NewAIMD(time.Millisecond, 5*time.Millisecond, time.Millisecond, 3)
+	require.NoError(t, err)
+	assert.Equal(t, time.Millisecond, a.Delay(), `Must start from the min delay`)
+
+	first, second := retry.Must(retry.WithAdaptiveBackoff(a)), retry.Must(retry.WithAdaptiveBackoff(a))
+
+	_ = first.Do(1, func() error { return errors.New(`discard`) })
+	assert.Equal(t, 3*time.Millisecond, a.Delay(), `Failure must multiplicatively increase the delay`)
+
+	_ = second.Do(2, func() error { return errors.New(`discard`) })
+	assert.Equal(t, 5*time.Millisecond, a.Delay(), `Delay must be capped by the max delay`)
+
+	assert.NoError(t, first.Do(1, func() error { return nil }))
+	assert.Equal(t, 4*time.Millisecond, a.Delay(), `Success must additively decrease the delay`)
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, second.Do(1, func() error { return nil }))
+	}
+	assert.Equal(t, time.Millisecond, a.Delay(), `Delay must not drop below the min delay`)
+}
@@ -0,0 +1,158 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// errSharedAttempts is wrapped by ExhaustedBudget when the attempts
+// shared by DoAll run out before a function has failed.
+var errSharedAttempts = errors.New(`shared attempts exhausted`)
+
+// FanOut configures how DoAll runs the functions passed to it.
+type FanOut struct {
+	// Concurrency limits how many functions are run at once,
+	// zero allows all of them to run at once.
+	Concurrency int
+	// Attempts limits the total number of attempts shared by all the functions,
+	// zero only limits each function by the limit passed to DoAll.
+	Attempts int
+	// CancelOnAbort cancels the context of every other function once any function
+	// has been aborted, running out of the shared attempts does not count as an abort.
+	CancelOnAbort bool
+}
+
+// Outcome is the result of a function run by DoAll.
+type Outcome struct {
+	Err      error
+	Attempts int
+}
+
+// DoAll runs every function concurrently, each being attempted up to limit times using the Retryer.
+// The outcome of each function is returned in the same order the functions were passed,
+// along with an error wrapping the first failure if any of the functions did not succeed.
+// Once the shared attempts of the FanOut are used up, any function that has not succeeded
+// is stopped with an ExhaustedBudget error.
+func DoAll(ctx context.Context, r Retryer, limit int, fo FanOut, fns ...func(ctx context.Context) error) ([]Outcome, error) {
+	if r == nil {
+		return nil, errors.New(`retryer is nil`)
+	}
+	if ctx == nil || ctx.Err() != nil {
+		return nil, errors.New(`invalid context provided`)
+	}
+	if fo.Concurrency < 0 || fo.Attempts < 0 {
+		return nil, errors.New(`fan out limits must not be negative`)
+	}
+	for _, f := range fns {
+		if f == nil {
+			return nil, errors.New(`invalid function provided`)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		used     int64
+		outcomes = make([]Outcome, len(fns))
+		slots    chan struct{}
+	)
+	if fo.Concurrency > 0 {
+		slots = make(chan struct{}, fo.Concurrency)
+	}
+
+	for i, f := range fns {
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				outcomes[i].Err = ctx.Err()
+				continue
+			}
+		}
+
+		wg.Add(1)
+		go func(o *Outcome, f func(ctx context.Context) error) {
+			defer wg.Done()
+			if slots != nil {
+				defer func() { <-slots }()
+			}
+
+			var (
+				// mu guards the state shared by the attempts,
+				// which a hedging Retryer makes concurrently
+				mu        sync.Mutex
+				last      error
+				exhausted bool
+				permitted int64
+				call      = ctx
+			)
+			if fo.Attempts > 0 {
+				// The shared attempts are checked before each attempt is started,
+				// so running out of them is never seen as a failed attempt
+				call = withPermit(ctx, func(attempt int, err error) error {
+					if atomic.AddInt64(&used, 1) > int64(fo.Attempts) {
+						if attempt == 1 {
+							err = errSharedAttempts
+						}
+						return ExhaustedBudget(err)
+					}
+					atomic.AddInt64(&permitted, 1)
+					return nil
+				})
+			}
+			o.Err = DoWithContextFunc(call, r, limit, func(ctx context.Context) error {
+				mu.Lock()
+				if fo.Attempts > 0 && atomic.LoadInt64(&permitted) <= int64(o.Attempts) &&
+					atomic.AddInt64(&used, 1) > int64(fo.Attempts) {
+					// The Retryer does not check the shared attempts before starting
+					// an attempt, so it is stopped by aborting with the last error instead
+					if last == nil {
+						last = errSharedAttempts
+					}
+					// Aborted only to stop the retryer, this is not a permanent failure
+					exhausted = true
+					err := last
+					mu.Unlock()
+					return AbortedRetries(ExhaustedBudget(err))
+				}
+				o.Attempts++
+				mu.Unlock()
+
+				err := f(ctx)
+				mu.Lock()
+				last = err
+				mu.Unlock()
+				return err
+			})
+			if o.Err != nil && o.Attempts == 0 && ctx.Err() != nil {
+				// Cancelled before any attempts could be made
+				o.Err = ctx.Err()
+			}
+			if fo.CancelOnAbort && HasAborted(o.Err) && !exhausted {
+				cancel()
+			}
+		}(&outcomes[i], f)
+	}
+	wg.Wait()
+
+	var (
+		failed int
+		first  error
+	)
+	for _, o := range outcomes {
+		if o.Err != nil {
+			if failed++; first == nil {
+				first = o.Err
+			}
+		}
+	}
+	if failed > 0 {
+		return outcomes, fmt.Errorf("%d of %d functions failed: %w", failed, len(fns), first)
+	}
+	return outcomes, nil
+}
@@ -0,0 +1,159 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+func TestInvalidDoAll(t *testing.T) {
+	t.Parallel()
+
+	ok := func(context.Context) error { return nil }
+
+	_, err := retry.DoAll(context.Background(), nil, 1, retry.FanOut{}, ok)
+	assert.Error(t, err, `Must not allow a nil retryer`)
+
+	_, err = retry.DoAll(nil, retry.Must(), 1, retry.FanOut{}, ok)
+	assert.Error(t, err, `Must not allow a nil context`)
+
+	_, err = retry.DoAll(context.Background(), retry.Must(), 1, retry.FanOut{Concurrency: -1}, ok)
+	assert.Error(t, err, `Must not allow negative limits`)
+
+	_, err = retry.DoAll(context.Background(), retry.Must(), 1, retry.FanOut{}, ok, nil)
+	assert.Error(t, err, `Must not allow a nil function`)
+}
+
+func TestDoAllOutcomes(t *testing.T) {
+	t.Parallel()
+
+	var running, peak int64
+	shard := func(fail bool) func(context.Context) error {
+		called := 0
+		return func(context.Context) error {
+			n := atomic.AddInt64(&running, 1)
+			defer atomic.AddInt64(&running, -1)
+			for {
+				p := atomic.LoadInt64(&peak)
+				if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			if called++; fail || called < 2 {
+				return errors.New(`discard`)
+			}
+			return nil
+		}
+	}
+
+	outcomes, err := retry.DoAll(context.Background(), retry.Must(), 3, retry.FanOut{Concurrency: 2},
+		shard(false), shard(true), shard(false), shard(false),
+	)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `1 of 4 functions failed`)
+	assert.True(t, retry.HasExceeded(err), `Must wrap the failure`)
+	assert.Equal(t, []retry.Outcome{
+		{Attempts: 2},
+		{Err: outcomes[1].Err, Attempts: 3},
+		{Attempts: 2},
+		{Attempts: 2},
+	}, outcomes)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&peak), `Must limit the concurrency`)
+
+	outcomes, err = retry.DoAll(context.Background(), retry.Must(), 3, retry.FanOut{}, shard(false), shard(false))
+	assert.NoError(t, err)
+	assert.Len(t, outcomes, 2)
+}
+
+func TestDoAllSharedAttempts(t *testing.T) {
+	t.Parallel()
+
+	var called int64
+	fail := func(context.Context) error {
+		atomic.AddInt64(&called, 1)
+		return errors.New(`discard`)
+	}
+
+	outcomes, err := retry.DoAll(context.Background(), retry.Must(), 5, retry.FanOut{Attempts: 4}, fail, fail)
+	assert.Error(t, err)
+	assert.Equal(t, int64(4), atomic.LoadInt64(&called), `Must not exceed the shared attempts`)
+	require.Len(t, outcomes, 2)
+	for _, o := range outcomes {
+		assert.True(t, retry.HasExhaustedBudget(o.Err), `Must stop once the shared attempts are used`)
+	}
+	assert.Equal(t, 4, outcomes[0].Attempts+outcomes[1].Attempts)
+}
+
+func TestDoAllCancelOnAbort(t *testing.T) {
+	t.Parallel()
+
+	outcomes, err := retry.DoAll(context.Background(), retry.Must(), 3, retry.FanOut{CancelOnAbort: true},
+		func(context.Context) error {
+			return retry.AbortedRetries(errors.New(`permanent`))
+		},
+		func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	)
+	assert.Error(t, err)
+	require.Len(t, outcomes, 2)
+	assert.True(t, retry.HasAborted(outcomes[0].Err))
+	assert.True(t, errors.Is(outcomes[1].Err, context.Canceled), `Other functions must be cancelled`)
+}
+
+func TestDoAllCancelOnAbortIgnoresSharedAttempts(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	outcomes, err := retry.DoAll(context.Background(), retry.Must(), 3, retry.FanOut{Attempts: 2, CancelOnAbort: true},
+		func(context.Context) error {
+			<-started
+			return errors.New(`discard`)
+		},
+		func(ctx context.Context) error {
+			close(started)
+			select {
+			case <-time.After(50 * time.Millisecond):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	)
+	assert.Error(t, err)
+	require.Len(t, outcomes, 2)
+	assert.True(t, retry.HasExhaustedBudget(outcomes[0].Err))
+	assert.NoError(t, outcomes[1].Err, `Running out of shared attempts must not cancel other functions`)
+}
+
+func TestDoAllSharedAttemptsNotReported(t *testing.T) {
+	t.Parallel()
+
+	cb := &countingBreaker{}
+	r := retry.Must(retry.WithCircuitBreaker(cb))
+	outcomes, err := retry.DoAll(context.Background(), r, 3, retry.FanOut{Attempts: 1}, func(context.Context) error {
+		return errors.New(`discard`)
+	})
+	assert.Error(t, err)
+	require.Len(t, outcomes, 1)
+	assert.True(t, retry.HasExhaustedBudget(outcomes[0].Err))
+	assert.Equal(t, 1, outcomes[0].Attempts)
+	assert.Len(t, cb.recorded(), 1, `Running out of shared attempts must not be reported to the breaker`)
+
+	outcomes, err = retry.DoAll(context.Background(), baseRetryer{r}, 3, retry.FanOut{Attempts: 1}, func(context.Context) error {
+		return errors.New(`discard`)
+	})
+	assert.Error(t, err)
+	require.Len(t, outcomes, 1)
+	assert.True(t, retry.HasExhaustedBudget(outcomes[0].Err), `Must limit the shared attempts of any Retryer`)
+	assert.Equal(t, 1, outcomes[0].Attempts)
+}
@@ -0,0 +1,93 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// BatchItem is the final outcome of an item passed to DoBatch.
+type BatchItem struct {
+	Item     interface{}
+	Err      error
+	Attempts int
+}
+
+// BatchFunc is called with the items that have not yet succeeded, and must return
+// an error for each of them in the same order, with nil for each item that succeeded.
+// An item error wrapped by AbortedRetries, or matched by WithAbortOn, stops any further
+// attempts for that item only.
+type BatchFunc func(ctx context.Context, items []interface{}) []error
+
+// DoBatch attempts the batch of items using the Retryer, with each attempt only retrying
+// the items that failed the previous attempt. The outcome of every item is returned in the
+// same order as the items were passed, along with an error if any of the items did not succeed.
+// The Retryer must not be configured with hedging, as attempts of a batch can not overlap.
+func DoBatch(ctx context.Context, r Retryer, limit int, items []interface{}, fn BatchFunc) ([]BatchItem, error) {
+	if r == nil {
+		return nil, errors.New(`retryer is nil`)
+	}
+	if fn == nil {
+		return nil, errors.New(`invalid function provided`)
+	}
+
+	results := make([]BatchItem, len(items))
+	pending := make([]int, len(items))
+	for i, item := range items {
+		results[i].Item = item
+		pending[i] = i
+	}
+
+	// Only the options of this package's Retryer can match item errors,
+	// any other Retryer only stops items that were returned as aborted.
+	abort := func(err error) error {
+		if HasAborted(err) {
+			return err
+		}
+		return nil
+	}
+	if ra, ok := r.(interface{ abort(err error) error }); ok {
+		abort = ra.abort
+	}
+
+	failed := 0
+	err := DoWithContextFunc(ctx, r, limit, func(ctx context.Context) error {
+		batch := make([]interface{}, len(pending))
+		for i, idx := range pending {
+			batch[i] = items[idx]
+		}
+
+		errs := fn(ctx, batch)
+		if len(errs) != len(batch) {
+			return AbortedRetries(fmt.Errorf("batch function returned %d errors for %d items", len(errs), len(batch)))
+		}
+
+		var remaining []int
+		for i, idx := range pending {
+			results[idx].Attempts++
+			results[idx].Err = errs[i]
+			if errs[i] == nil {
+				continue
+			}
+			if aborted := abort(errs[i]); aborted != nil {
+				results[idx].Err = aborted
+				failed++
+				continue
+			}
+			remaining = append(remaining, idx)
+		}
+		pending = remaining
+
+		if len(pending) > 0 {
+			return fmt.Errorf("%d of %d items failed", len(pending), len(batch))
+		}
+		return nil
+	})
+	if err != nil {
+		return results, err
+	}
+	if failed > 0 {
+		return results, AbortedRetries(fmt.Errorf("%d of %d items failed", failed, len(items)))
+	}
+	return results, nil
+}
@@ -0,0 +1,105 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+func TestInvalidBatch(t *testing.T) {
+	t.Parallel()
+
+	_, err := retry.DoBatch(context.Background(), nil, 1, nil, func(context.Context, []interface{}) []error { return nil })
+	assert.Error(t, err, `Must not allow a nil retryer`)
+
+	_, err = retry.DoBatch(context.Background(), retry.Must(), 1, nil, nil)
+	assert.Error(t, err, `Must not allow a nil function`)
+
+	_, err = retry.DoBatch(context.Background(), retry.Must(), 3, []interface{}{1, 2}, func(context.Context, []interface{}) []error {
+		return nil
+	})
+	assert.True(t, retry.HasAborted(err), `Must abort when the errors do not match the items`)
+}
+
+func TestBatchRetriesFailedItems(t *testing.T) {
+	t.Parallel()
+
+	var (
+		batches [][]interface{}
+		items   = []interface{}{`a`, `b`, `c`, `d`}
+	)
+	results, err := retry.DoBatch(context.Background(), retry.Must(), 3, items, func(_ context.Context, batch []interface{}) []error {
+		batches = append(batches, batch)
+		errs := make([]error, len(batch))
+		for i, item := range batch {
+			switch {
+			case item == `b` && len(batches) < 2:
+				errs[i] = errors.New(`transient`)
+			case item == `c`:
+				errs[i] = retry.AbortedRetries(errors.New(`permanent`))
+			case item == `d`:
+				errs[i] = errors.New(`transient`)
+			}
+		}
+		return errs
+	})
+	assert.True(t, retry.HasExceeded(err), `Must report items that did not succeed`)
+	assert.Equal(t, [][]interface{}{{`a`, `b`, `c`, `d`}, {`b`, `d`}, {`d`}}, batches, `Must only retry the failed items`)
+
+	require.Len(t, results, 4)
+	assert.Equal(t, retry.BatchItem{Item: `a`, Attempts: 1}, results[0])
+	assert.Equal(t, retry.BatchItem{Item: `b`, Attempts: 2}, results[1])
+	assert.True(t, retry.HasAborted(results[2].Err))
+	assert.Equal(t, 1, results[2].Attempts, `Permanent errors must stop retries for the item only`)
+	assert.EqualError(t, results[3].Err, `transient`)
+	assert.Equal(t, 3, results[3].Attempts)
+
+	results, err = retry.DoBatch(context.Background(), retry.Must(), 3, items[:3], func(_ context.Context, batch []interface{}) []error {
+		errs := make([]error, len(batch))
+		for i, item := range batch {
+			if item == `c` {
+				errs[i] = retry.AbortedRetries(errors.New(`permanent`))
+			}
+		}
+		return errs
+	})
+	assert.True(t, retry.HasAborted(err), `Must report items that failed permanently`)
+	assert.NoError(t, results[0].Err)
+
+	results, err = retry.DoBatch(context.Background(), retry.Must(), 1, items, func(_ context.Context, batch []interface{}) []error {
+		return make([]error, len(batch))
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 4)
+}
+
+func TestBatchAbortOn(t *testing.T) {
+	t.Parallel()
+
+	permanent := errors.New(`permanent`)
+	r := retry.Must(retry.WithAbortOn(func(err error) bool { return errors.Is(err, permanent) }))
+
+	attempts := 0
+	results, err := retry.DoBatch(context.Background(), r, 3, []interface{}{`a`, `b`}, func(_ context.Context, batch []interface{}) []error {
+		attempts++
+		errs := make([]error, len(batch))
+		for i, item := range batch {
+			if item == `b` {
+				errs[i] = permanent
+			}
+		}
+		return errs
+	})
+	assert.True(t, retry.HasAborted(err), `Must report items that matched the abort`)
+	assert.Equal(t, 1, attempts, `Matched items must not be retried`)
+
+	require.Len(t, results, 2)
+	assert.NoError(t, results[0].Err)
+	assert.True(t, retry.HasAborted(results[1].Err), `Matched item errors must be aborted`)
+	assert.True(t, errors.Is(results[1].Err, permanent))
+}
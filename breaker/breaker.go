@@ -0,0 +1,212 @@
+// Package breaker implements a circuit breaker that can be attached to a Retryer
+// so that calls to a failing dependency fail fast instead of being retried.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+// State is the current state of the circuit breaker.
+type State int
+
+const (
+	// Closed allows all attempts through while tracking failures.
+	Closed State = iota
+	// Open rejects all attempts until the cool down period has passed.
+	Open
+	// HalfOpen allows a limited number of probe attempts through
+	// to determine if the breaker can be closed again.
+	HalfOpen
+)
+
+var (
+	text = map[State]string{
+		Closed:   "closed",
+		Open:     "open",
+		HalfOpen: "half-open",
+	}
+)
+
+func (s State) String() string {
+	if t, ok := text[s]; ok {
+		return t
+	}
+	return "unknown"
+}
+
+// ErrOpen is returned when an attempt is rejected by the circuit breaker.
+var ErrOpen = errors.New(`circuit breaker is open`)
+
+// Breaker is a circuit breaker that is safe to share between many Retryers.
+type Breaker struct {
+	mu sync.Mutex
+
+	consecutive int
+	rate        float64
+	minRequests int
+	window      time.Duration
+	coolDown    time.Duration
+	probes      int
+	onChange    func(from, to State)
+
+	state    State
+	failures int
+	requests int
+	failed   int
+	started  time.Time
+	opened   time.Time
+	inflight int
+	passed   int
+	// generation is incremented on every transition so that
+	// the results of attempts started before it can be ignored.
+	generation uint64
+}
+
+var _ retry.CircuitBreaker = (*Breaker)(nil)
+
+// New creates a closed circuit breaker with the provided options.
+// Without any threshold options, the breaker opens after 5 consecutive failures.
+// An error is returned if any of the options failed to apply
+func New(opts ...Option) (*Breaker, error) {
+	b := &Breaker{
+		coolDown: 30 * time.Second,
+		probes:   1,
+		started:  time.Now(),
+	}
+	for _, opt := range opts {
+		if err := opt(b); err != nil {
+			return nil, err
+		}
+	}
+	if b.consecutive == 0 && b.rate == 0 {
+		b.consecutive = 5
+	}
+	return b, nil
+}
+
+// Must is a convenience function for New that will panic
+// if an error was to be returned.
+func Must(opts ...Option) *Breaker {
+	b, err := New(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cooled(time.Now())
+	return b.state
+}
+
+// Allow implements retry.CircuitBreaker
+func (b *Breaker) Allow() (func(err error), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.cooled(time.Now())
+	switch b.state {
+	case Open:
+		return nil, ErrOpen
+	case HalfOpen:
+		if b.inflight >= b.probes {
+			return nil, ErrOpen
+		}
+		b.inflight++
+	}
+
+	// Capture the generation the attempt was started under, so results
+	// from before a transition, even into the same state, are not miscounted.
+	generation, once := b.generation, sync.Once{}
+	return func(err error) {
+		once.Do(func() {
+			b.record(generation, err)
+		})
+	}, nil
+}
+
+// cooled moves an open breaker into half open
+// once the cool down period has passed.
+func (b *Breaker) cooled(now time.Time) {
+	if b.state == Open && now.Sub(b.opened) >= b.coolDown {
+		b.transition(HalfOpen, now)
+	}
+}
+
+func (b *Breaker) record(generation uint64, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if generation != b.generation {
+		return
+	}
+	// A cancelled attempt says nothing about the dependency,
+	// so it only releases its probe without being counted.
+	cancelled := errors.Is(err, context.Canceled)
+
+	switch b.state {
+	case HalfOpen:
+		b.inflight--
+		if cancelled {
+			return
+		}
+		if err != nil {
+			b.transition(Open, now)
+			return
+		}
+		if b.passed++; b.passed >= b.probes {
+			b.transition(Closed, now)
+		}
+	case Closed:
+		if b.window > 0 && now.Sub(b.started) >= b.window {
+			b.requests, b.failed, b.started = 0, 0, now
+		}
+		if cancelled {
+			return
+		}
+		b.requests++
+		if err == nil {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		b.failed++
+		if b.tripped() {
+			b.transition(Open, now)
+		}
+	}
+}
+
+func (b *Breaker) tripped() bool {
+	if b.consecutive > 0 && b.failures >= b.consecutive {
+		return true
+	}
+	if b.rate > 0 && b.requests >= b.minRequests {
+		return float64(b.failed)/float64(b.requests) >= b.rate
+	}
+	return false
+}
+
+func (b *Breaker) transition(to State, now time.Time) {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.failures, b.requests, b.failed = 0, 0, 0
+	b.inflight, b.passed = 0, 0
+	b.started = now
+	if to == Open {
+		b.opened = now
+	}
+	if b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
@@ -0,0 +1,187 @@
+package breaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+	"github.com/MovieStoreGuy/retry/breaker"
+)
+
+func TestInvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	invalid := []breaker.Option{
+		breaker.WithConsecutiveFailures(0),
+		breaker.WithFailureRate(0, 1, time.Second),
+		breaker.WithFailureRate(1.2, 1, time.Second),
+		breaker.WithFailureRate(0.5, 0, time.Second),
+		breaker.WithFailureRate(0.5, 1, 0),
+		breaker.WithCoolDown(0),
+		breaker.WithHalfOpenProbes(0),
+		breaker.WithStateChange(nil),
+	}
+
+	for _, opt := range invalid {
+		_, err := breaker.New(opt)
+		assert.Error(t, err)
+		assert.Panics(t, func() {
+			_ = breaker.Must(opt)
+		})
+	}
+
+	_, err := retry.New(retry.WithCircuitBreaker(nil))
+	assert.Error(t, err, `Must not allow a nil circuit breaker`)
+}
+
+func TestStateText(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `closed`, breaker.Closed.String())
+	assert.Equal(t, `open`, breaker.Open.String())
+	assert.Equal(t, `half-open`, breaker.HalfOpen.String())
+	assert.Equal(t, `unknown`, breaker.State(-1).String())
+}
+
+func TestConsecutiveFailures(t *testing.T) {
+	t.Parallel()
+
+	var changes []breaker.State
+	b := breaker.Must(
+		breaker.WithConsecutiveFailures(3),
+		breaker.WithCoolDown(20*time.Millisecond),
+		breaker.WithHalfOpenProbes(2),
+		breaker.WithStateChange(func(_, to breaker.State) {
+			changes = append(changes, to)
+		}),
+	)
+	r := retry.Must(retry.WithCircuitBreaker(b))
+
+	called := 0
+	err := r.Do(5, func() error {
+		called++
+		return errors.New(`discard`)
+	})
+	assert.True(t, retry.HasAborted(err), `Open circuit must abort retries`)
+	assert.True(t, errors.Is(err, breaker.ErrOpen), `Must be able to recognise the open circuit`)
+	assert.Equal(t, 3, called)
+	assert.Equal(t, breaker.Open, b.State())
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, breaker.HalfOpen, b.State(), `Must allow probes after cooling down`)
+
+	first, err := b.Allow()
+	require.NoError(t, err)
+	second, err := b.Allow()
+	require.NoError(t, err)
+	_, err = b.Allow()
+	assert.Equal(t, breaker.ErrOpen, err, `Must limit the number of probes`)
+
+	first(nil)
+	assert.Equal(t, breaker.HalfOpen, b.State(), `Must wait for all probes to succeed`)
+	second(nil)
+	assert.Equal(t, breaker.Closed, b.State())
+
+	assert.Equal(t, []breaker.State{breaker.Open, breaker.HalfOpen, breaker.Closed}, changes)
+}
+
+func TestFailedProbeReopens(t *testing.T) {
+	t.Parallel()
+
+	b := breaker.Must(breaker.WithConsecutiveFailures(1), breaker.WithCoolDown(10*time.Millisecond))
+	r := retry.Must(retry.WithCircuitBreaker(b))
+
+	_ = r.Do(1, func() error { return errors.New(`discard`) })
+	assert.Equal(t, breaker.Open, b.State())
+
+	time.Sleep(20 * time.Millisecond)
+	called := 0
+	err := r.Do(3, func() error {
+		called++
+		return errors.New(`discard`)
+	})
+	assert.True(t, errors.Is(err, breaker.ErrOpen))
+	assert.Equal(t, 1, called, `Only the probe attempt must be made`)
+	assert.Equal(t, breaker.Open, b.State(), `Failed probe must open the breaker again`)
+}
+
+func TestStaleProbeIgnored(t *testing.T) {
+	t.Parallel()
+
+	b := breaker.Must(
+		breaker.WithConsecutiveFailures(1),
+		breaker.WithCoolDown(10*time.Millisecond),
+		breaker.WithHalfOpenProbes(2),
+	)
+	done, err := b.Allow()
+	require.NoError(t, err)
+	done(errors.New(`discard`))
+
+	time.Sleep(20 * time.Millisecond)
+	failed, err := b.Allow()
+	require.NoError(t, err)
+	stale, err := b.Allow()
+	require.NoError(t, err)
+	failed(errors.New(`discard`))
+	assert.Equal(t, breaker.Open, b.State())
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = b.Allow()
+	require.NoError(t, err)
+	stale(errors.New(`discard`))
+	assert.Equal(t, breaker.HalfOpen, b.State(), `Must ignore probes from an earlier half open period`)
+}
+
+func TestFailureRate(t *testing.T) {
+	t.Parallel()
+
+	b := breaker.Must(breaker.WithFailureRate(0.5, 4, time.Minute))
+	r := retry.Must(retry.WithCircuitBreaker(b))
+
+	for _, fail := range []bool{false, true, false} {
+		_ = r.Do(1, func() error {
+			if fail {
+				return errors.New(`discard`)
+			}
+			return nil
+		})
+	}
+	assert.Equal(t, breaker.Closed, b.State(), `Must wait for the min number of requests`)
+
+	_ = r.Do(1, func() error { return errors.New(`discard`) })
+	assert.Equal(t, breaker.Open, b.State(), `Must open once the failure rate is reached`)
+}
+
+func TestCancelledAttemptsNotCounted(t *testing.T) {
+	t.Parallel()
+
+	b := breaker.Must(
+		breaker.WithConsecutiveFailures(1),
+		breaker.WithCoolDown(time.Millisecond),
+	)
+
+	done, err := b.Allow()
+	require.NoError(t, err)
+	done(context.Canceled)
+	assert.Equal(t, breaker.Closed, b.State(), `Cancelled attempts must not trip the breaker`)
+
+	done, err = b.Allow()
+	require.NoError(t, err)
+	done(errors.New(`discard`))
+	require.Eventually(t, func() bool { return b.State() == breaker.HalfOpen }, time.Second, time.Millisecond)
+
+	done, err = b.Allow()
+	require.NoError(t, err)
+	done(context.Canceled)
+	assert.Equal(t, breaker.HalfOpen, b.State(), `Cancelled probes must not close or open the breaker`)
+
+	done, err = b.Allow()
+	require.NoError(t, err, `Cancelled probes must be released`)
+	done(nil)
+	assert.Equal(t, breaker.Closed, b.State())
+}
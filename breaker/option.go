@@ -0,0 +1,75 @@
+package breaker
+
+import (
+	"errors"
+	"time"
+)
+
+// Option allows for the circuit breaker to be configured on creation.
+type Option func(b *Breaker) error
+
+// WithConsecutiveFailures opens the breaker once the limit
+// of consecutive failed attempts has been reached.
+func WithConsecutiveFailures(limit int) Option {
+	return func(b *Breaker) error {
+		if limit < 1 {
+			return errors.New(`consecutive failures must be positive`)
+		}
+		b.consecutive = limit
+		return nil
+	}
+}
+
+// WithFailureRate opens the breaker once the ratio of failed attempts reaches rate,
+// with at least minRequests attempts made within the window.
+func WithFailureRate(rate float64, minRequests int, window time.Duration) Option {
+	return func(b *Breaker) error {
+		if rate <= 0 || rate > 1 {
+			return errors.New(`failure rate must be between 0 and 1`)
+		}
+		if minRequests < 1 {
+			return errors.New(`min requests must be positive`)
+		}
+		if window <= 0 {
+			return errors.New(`window must be a positive value`)
+		}
+		b.rate, b.minRequests, b.window = rate, minRequests, window
+		return nil
+	}
+}
+
+// WithCoolDown sets how long the breaker stays open
+// before allowing probe attempts through.
+func WithCoolDown(d time.Duration) Option {
+	return func(b *Breaker) error {
+		if d <= 0 {
+			return errors.New(`cool down must be a positive value`)
+		}
+		b.coolDown = d
+		return nil
+	}
+}
+
+// WithHalfOpenProbes sets the number of concurrent probe attempts allowed while half open,
+// all of which must succeed for the breaker to close again.
+func WithHalfOpenProbes(limit int) Option {
+	return func(b *Breaker) error {
+		if limit < 1 {
+			return errors.New(`half open probes must be positive`)
+		}
+		b.probes = limit
+		return nil
+	}
+}
+
+// WithStateChange calls the function each time the breaker changes state.
+// The function is called while the breaker is locked, so it must not call back into it.
+func WithStateChange(f func(from, to State)) Option {
+	return func(b *Breaker) error {
+		if f == nil {
+			return errors.New(`state change function must not be nil`)
+		}
+		b.onChange = f
+		return nil
+	}
+}
@@ -0,0 +1,94 @@
+package retry
+
+import (
+	"errors"
+	"sync"
+)
+
+// RetryBudget is a token bucket that can be shared between many Retryers,
+// and transports, to stop an outage of a dependency from multiplying
+// the traffic sent to it.
+// Every retry spends a token and every successful attempt deposits a fraction
+// of a token, once the bucket is empty only the first attempt is made.
+type RetryBudget struct {
+	mu     sync.Mutex
+	max    float64
+	ratio  float64
+	tokens float64
+}
+
+// NewRetryBudget creates a full budget that can hold at most max tokens,
+// with ratio being the fraction of a token deposited by each successful attempt.
+func NewRetryBudget(max, ratio float64) (*RetryBudget, error) {
+	if max < 1 {
+		return nil, errors.New(`max tokens must be at least 1`)
+	}
+	if ratio <= 0 || ratio > 1 {
+		return nil, errors.New(`ratio must be between 0 and 1`)
+	}
+	return &RetryBudget{
+		max:    max,
+		ratio:  ratio,
+		tokens: max,
+	}, nil
+}
+
+// Tokens returns the number of tokens currently held by the budget.
+func (b *RetryBudget) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens
+}
+
+func (b *RetryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens += b.ratio; b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
+
+// refund returns a withdrawn token for a retry that was never made.
+func (b *RetryBudget) refund() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens++; b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
+
+func (b *RetryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// WithRetryBudget spends a token from the shared budget before waiting to make each retry,
+// and stops any further attempts with an exhausted budget error once it is empty.
+// The token is returned if the context is done before the retry is made.
+func WithRetryBudget(b *RetryBudget) Option {
+	return func(r *retry) error {
+		if b == nil {
+			return errors.New(`retry budget must not be nil`)
+		}
+		r.permits = append(r.permits, func(attempt int, last error) (func(), error) {
+			if attempt == 1 {
+				return nil, nil
+			}
+			if !b.withdraw() {
+				return nil, ExhaustedBudget(last)
+			}
+			return b.refund, nil
+		})
+		r.observers = append(r.observers, func(err error) {
+			if err == nil {
+				b.deposit()
+			}
+		})
+		return nil
+	}
+}
@@ -0,0 +1,131 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+func TestInvalidRetryBudget(t *testing.T) {
+	t.Parallel()
+
+	for _, args := range [][2]float64{{0, 0.1}, {10, 0}, {10, 1.5}} {
+		b, err := retry.NewRetryBudget(args[0], args[1])
+		assert.Error(t, err)
+		assert.Nil(t, b)
+	}
+
+	_, err := retry.New(retry.WithRetryBudget(nil))
+	assert.Error(t, err, `Must not allow a nil retry budget`)
+}
+
+func TestRetryBudgetSharedBetweenRetryers(t *testing.T) {
+	t.Parallel()
+
+	b, err := retry.NewRetryBudget(3, 0.5)
+	require.NoError(t, err)
+
+	first, second := retry.Must(retry.WithRetryBudget(b)), retry.Must(retry.WithRetryBudget(b))
+
+	called := 0
+	err = first.Do(3, func() error {
+		called++
+		return errors.New(`discard`)
+	})
+	assert.True(t, retry.HasExceeded(err), `Budget has enough tokens for all retries`)
+	assert.Equal(t, 3, called)
+	assert.Equal(t, 1.0, b.Tokens())
+
+	called = 0
+	err = second.Do(5, func() error {
+		called++
+		return errors.New(`discard`)
+	})
+	assert.True(t, retry.HasExhaustedBudget(err), `Budget must stop retries once empty`)
+	assert.EqualError(t, errors.Unwrap(err), `discard`, `Must wrap the last error`)
+	assert.Equal(t, 2, called)
+
+	called = 0
+	err = first.Do(5, func() error {
+		called++
+		return errors.New(`discard`)
+	})
+	assert.True(t, retry.HasExhaustedBudget(err))
+	assert.Equal(t, 1, called, `Must only make the first attempt with an empty budget`)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, second.Do(1, func() error { return nil }))
+	}
+	assert.Equal(t, 3.0, b.Tokens(), `Successes must refill the budget up to the max`)
+}
+
+func TestRetryBudgetFailsBeforeWaiting(t *testing.T) {
+	t.Parallel()
+
+	s, err := retry.NewScheduler(1)
+	require.NoError(t, err)
+	defer s.Stop()
+
+	for name, opts := range map[string][]retry.Option{
+		`attempts`:  nil,
+		`hedged`:    {retry.WithHedging(time.Hour, 1)},
+		`scheduled`: {retry.WithScheduler(s)},
+	} {
+		b, err := retry.NewRetryBudget(1, 0.5)
+		require.NoError(t, err)
+		_ = retry.Must(retry.WithRetryBudget(b)).Do(2, func() error { return errors.New(`discard`) })
+		require.Equal(t, 0.0, b.Tokens())
+
+		r := retry.Must(append(opts, retry.WithRetryBudget(b), retry.WithFixedDelay(time.Hour))...)
+		fu := retry.DoAsync(context.Background(), r, 3, func(context.Context) error {
+			return errors.New(`discard`)
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err = fu.Wait(ctx)
+		cancel()
+		fu.Cancel()
+		assert.True(t, retry.HasExhaustedBudget(err), `Must not wait for the delay of a retry that is not permitted when %s`, name)
+	}
+}
+
+func TestRetryBudgetRefundsCancelledRetries(t *testing.T) {
+	t.Parallel()
+
+	s, err := retry.NewScheduler(1)
+	require.NoError(t, err)
+	defer s.Stop()
+
+	for name, opts := range map[string][]retry.Option{
+		`attempts`:  nil,
+		`hedged`:    {retry.WithHedging(time.Hour, 1)},
+		`scheduled`: {retry.WithScheduler(s)},
+	} {
+		b, err := retry.NewRetryBudget(2, 0.5)
+		require.NoError(t, err)
+		sd, err := retry.NewStormDetector(`storm`, time.Minute, 10, nil)
+		require.NoError(t, err)
+
+		r := retry.Must(append(opts, retry.WithRetryBudget(b), retry.WithStormDetector(sd), retry.WithFixedDelay(time.Hour))...)
+		ctx, cancel := context.WithCancel(context.Background())
+		attempted := make(chan struct{})
+		fu := retry.DoAsync(ctx, r, 3, func(context.Context) error {
+			close(attempted)
+			return errors.New(`discard`)
+		})
+		<-attempted
+		require.Eventually(t, func() bool { return b.Tokens() == 1 }, time.Second, time.Millisecond, `Retry must be permitted before waiting when %s`, name)
+
+		cancel()
+		wait, stop := context.WithTimeout(context.Background(), time.Second)
+		assert.Equal(t, context.Canceled, fu.Wait(wait), name)
+		stop()
+		assert.Equal(t, 2.0, b.Tokens(), `Must refund the token of a retry that was not made when %s`, name)
+		assert.Equal(t, 0.0, sd.State().Ratio, `Must not count a retry that was not made when %s`, name)
+	}
+}
@@ -0,0 +1,133 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrBulkheadFull is wrapped by RejectedAttempt when the bulkhead
+	// has no capacity left to queue the attempt.
+	ErrBulkheadFull = errors.New(`bulkhead is full`)
+	// ErrBulkheadTimeout is wrapped by RejectedAttempt when the attempt
+	// has waited in the queue for longer than the queue timeout.
+	ErrBulkheadTimeout = errors.New(`bulkhead queue timed out`)
+)
+
+// Bulkhead limits the number of attempts that are allowed to run at once,
+// holding any excess attempts in a bounded wait queue until capacity is free.
+// A Bulkhead can be shared between many Retryers to limit them together.
+type Bulkhead struct {
+	slots   chan struct{}
+	queue   chan struct{}
+	timeout time.Duration
+}
+
+// NewBulkhead creates a bulkhead that allows limit concurrent attempts,
+// with at most queue attempts waiting for up to timeout for their turn.
+func NewBulkhead(limit, queue int, timeout time.Duration) (*Bulkhead, error) {
+	if limit < 1 {
+		return nil, errors.New(`limit must be positive`)
+	}
+	if queue < 0 {
+		return nil, errors.New(`queue must not be negative`)
+	}
+	if queue > 0 && timeout <= 0 {
+		return nil, errors.New(`queue timeout must be a positive value`)
+	}
+	return &Bulkhead{
+		slots:   make(chan struct{}, limit),
+		queue:   make(chan struct{}, queue),
+		timeout: timeout,
+	}, nil
+}
+
+// Running returns the number of attempts that currently hold the bulkhead.
+func (b *Bulkhead) Running() int {
+	return len(b.slots)
+}
+
+func (b *Bulkhead) acquire(ctx context.Context) error {
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	default:
+		// All slots are taken, attempt must wait in the queue
+	}
+
+	select {
+	case b.queue <- struct{}{}:
+		defer func() { <-b.queue }()
+	default:
+		return RejectedAttempt(ErrBulkheadFull)
+	}
+
+	t := time.NewTimer(b.timeout)
+	defer t.Stop()
+
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-t.C:
+		return RejectedAttempt(ErrBulkheadTimeout)
+	case <-ctx.Done():
+		return AbortedRetries(ctx.Err())
+	}
+}
+
+func (b *Bulkhead) release() {
+	<-b.slots
+}
+
+// BulkheadGroup lazily creates a bulkhead for each key,
+// so Retryers using the same key share the same limits.
+type BulkheadGroup struct {
+	mu      sync.Mutex
+	limit   int
+	queue   int
+	timeout time.Duration
+	heads   map[string]*Bulkhead
+}
+
+// NewBulkheadGroup creates a group where each key is limited
+// as if created by NewBulkhead with the same values.
+func NewBulkheadGroup(limit, queue int, timeout time.Duration) (*BulkheadGroup, error) {
+	// Validate the values early on rather than on first use
+	if _, err := NewBulkhead(limit, queue, timeout); err != nil {
+		return nil, err
+	}
+	return &BulkheadGroup{
+		limit:   limit,
+		queue:   queue,
+		timeout: timeout,
+		heads:   make(map[string]*Bulkhead),
+	}, nil
+}
+
+// Get returns the bulkhead for the key, creating it if it does not exist.
+func (g *BulkheadGroup) Get(key string) *Bulkhead {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	b, exist := g.heads[key]
+	if !exist {
+		b, _ = NewBulkhead(g.limit, g.queue, g.timeout)
+		g.heads[key] = b
+	}
+	return b
+}
+
+// WithBulkhead makes each attempt hold the bulkhead while it runs.
+// Attempts rejected by the bulkhead return a RejectedAttempt error which is retried
+// like any other failed attempt, use WithAbortOn(HasRejected) to stop retrying them instead.
+func WithBulkhead(b *Bulkhead) Option {
+	return func(r *retry) error {
+		p, err := BulkheadPolicy(b)
+		if err != nil {
+			return err
+		}
+		r.interceptors = append(r.interceptors, interceptor(p))
+		return nil
+	}
+}
@@ -0,0 +1,102 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+func TestInvalidBulkhead(t *testing.T) {
+	t.Parallel()
+
+	_, err := retry.NewBulkhead(0, 0, 0)
+	assert.Error(t, err, `Must not allow a non positive limit`)
+
+	_, err = retry.NewBulkhead(1, -1, 0)
+	assert.Error(t, err, `Must not allow a negative queue`)
+
+	_, err = retry.NewBulkhead(1, 1, 0)
+	assert.Error(t, err, `Must not allow a queue without a timeout`)
+
+	_, err = retry.NewBulkheadGroup(0, 0, 0)
+	assert.Error(t, err, `Must validate group values`)
+
+	_, err = retry.New(retry.WithBulkhead(nil))
+	assert.Error(t, err, `Must not allow a nil bulkhead`)
+}
+
+func TestBulkheadRejectsAttempts(t *testing.T) {
+	t.Parallel()
+
+	b, err := retry.NewBulkhead(1, 1, 20*time.Millisecond)
+	require.NoError(t, err)
+
+	var (
+		wg      sync.WaitGroup
+		hold    = make(chan struct{})
+		started = make(chan struct{})
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = retry.Must(retry.WithBulkhead(b)).Do(1, func() error {
+			close(started)
+			<-hold
+			return nil
+		})
+	}()
+	<-started
+	assert.Equal(t, 1, b.Running())
+
+	called := 0
+	err = retry.Must(retry.WithBulkhead(b), retry.WithAbortOn(retry.HasRejected)).Do(3, func() error {
+		called++
+		return nil
+	})
+	assert.True(t, retry.HasRejected(err), `Must be rejected by the full bulkhead`)
+	assert.True(t, retry.HasAborted(err), `Rejected attempt must not be retried`)
+	assert.True(t, errors.Is(err, retry.ErrBulkheadTimeout))
+	assert.Equal(t, 0, called)
+
+	close(hold)
+	wg.Wait()
+
+	assert.NoError(t, retry.Must(retry.WithBulkhead(b)).Do(1, func() error {
+		assert.Equal(t, 1, b.Running())
+		return nil
+	}))
+	assert.Equal(t, 0, b.Running(), `Must release the bulkhead after the attempt`)
+}
+
+func TestBulkheadFullQueue(t *testing.T) {
+	t.Parallel()
+
+	b, err := retry.NewBulkhead(1, 0, 0)
+	require.NoError(t, err)
+
+	err = retry.Must(retry.WithBulkhead(b)).DoWithContext(context.Background(), 1, func() error {
+		return retry.Must(retry.WithBulkhead(b)).Do(2, func() error {
+			return nil
+		})
+	})
+	assert.True(t, retry.HasRejected(err))
+	assert.True(t, errors.Is(err, retry.ErrBulkheadFull))
+	assert.True(t, retry.HasExceeded(err), `Rejected attempts are retried by default`)
+}
+
+func TestBulkheadGroupSharesKeys(t *testing.T) {
+	t.Parallel()
+
+	g, err := retry.NewBulkheadGroup(2, 0, 0)
+	require.NoError(t, err)
+
+	assert.Same(t, g.Get(`payments`), g.Get(`payments`))
+	assert.NotSame(t, g.Get(`payments`), g.Get(`orders`))
+}
@@ -0,0 +1,333 @@
+package retry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MovieStoreGuy/retry/internal/journal"
+)
+
+// ErrLetterNotFound is returned when removing a letter that does not exist.
+var ErrLetterNotFound = errors.New(`letter not found`)
+
+// Attempt is the recorded outcome of a single attempt.
+type Attempt struct {
+	Number int       `json:"number"`
+	Time   time.Time `json:"time"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Letter holds the payload of work that was given up on,
+// along with the history of every attempt that was made for it.
+type Letter struct {
+	ID       string    `json:"id"`
+	Payload  []byte    `json:"payload"`
+	Error    string    `json:"error"`
+	Time     time.Time `json:"time"`
+	Attempts []Attempt `json:"attempts"`
+}
+
+// NewLetter creates a letter with a unique ID for the payload,
+// recording err as the reason it was given up on.
+func NewLetter(payload []byte, err error, attempts []Attempt) Letter {
+	id := make([]byte, 16)
+	// Read only fails if the system source of randomness is unavailable,
+	// the time based fallback still ensures the id is usable
+	if _, rerr := rand.Read(id); rerr != nil {
+		id = []byte(time.Now().Format(time.RFC3339Nano))
+	}
+	l := Letter{
+		ID:       hex.EncodeToString(id),
+		Payload:  payload,
+		Time:     time.Now(),
+		Attempts: attempts,
+	}
+	if err != nil {
+		l.Error = err.Error()
+	}
+	return l
+}
+
+// DeadLetter stores the letters of work that has been given up on,
+// so it can be inspected and redriven at a later time.
+type DeadLetter interface {
+	// Put stores the letter.
+	Put(ctx context.Context, l Letter) error
+	// List returns all the stored letters, oldest first.
+	List(ctx context.Context) ([]Letter, error)
+	// Remove deletes the letter with the matching ID,
+	// returning ErrLetterNotFound if it does not exist.
+	Remove(ctx context.Context, id string) error
+}
+
+// Redrive passes each stored letter to submit, oldest first, removing
+// every letter that was submitted successfully. It stops on the first error,
+// returning the number of letters that were redriven.
+func Redrive(ctx context.Context, dl DeadLetter, submit func(ctx context.Context, l Letter) error) (int, error) {
+	if dl == nil {
+		return 0, errors.New(`dead letter must not be nil`)
+	}
+	if submit == nil {
+		return 0, errors.New(`submit function must not be nil`)
+	}
+	letters, err := dl.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i, l := range letters {
+		if err := ctx.Err(); err != nil {
+			return i, err
+		}
+		if err := submit(ctx, l); err != nil {
+			return i, err
+		}
+		if err := dl.Remove(ctx, l.ID); err != nil {
+			return i, err
+		}
+	}
+	return len(letters), nil
+}
+
+type payloadKey struct{}
+
+// WithPayload returns a context that carries the payload of the work being attempted,
+// which is written to the dead letter if the attempts are given up on.
+func WithPayload(ctx context.Context, payload []byte) context.Context {
+	return context.WithValue(ctx, payloadKey{}, payload)
+}
+
+type skipKey struct{}
+
+// WithoutDeadLetter returns a context that stops the attempts made with it from being written
+// to the dead letter of the Retryer, for callers that keep their own record of the attempts
+// such as a queue that makes each attempt of a job as a separate call.
+// The context passed to each attempt no longer skips the dead letter, so any Retryer
+// used within an attempt still writes to its own dead letter.
+func WithoutDeadLetter(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipKey{}, true)
+}
+
+// history records each attempt made so that it can be written to a dead letter.
+type history struct {
+	mu       sync.Mutex
+	attempts []Attempt
+}
+
+func (h *history) letter(ctx context.Context, err error) Letter {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	payload, _ := ctx.Value(payloadKey{}).([]byte)
+	return NewLetter(payload, err, append([]Attempt(nil), h.attempts...))
+}
+
+// record wraps f to record the history of each attempt if a dead letter is configured
+// and not skipped by the context, returning the context to make the attempts with.
+func (r *retry) record(ctx context.Context, f func(ctx context.Context) error) (context.Context, func(ctx context.Context) error, *history) {
+	if skip, _ := ctx.Value(skipKey{}).(bool); skip {
+		// Only the attempts of this call skip the dead letter
+		return context.WithValue(ctx, skipKey{}, false), f, nil
+	}
+	if r.deadLetter == nil {
+		return ctx, f, nil
+	}
+	h := &history{}
+	return ctx, func(ctx context.Context) error {
+		started := time.Now()
+		err := f(ctx)
+
+		h.mu.Lock()
+		a := Attempt{Number: len(h.attempts) + 1, Time: started}
+		if err != nil {
+			a.Error = err.Error()
+		}
+		h.attempts = append(h.attempts, a)
+		h.mu.Unlock()
+		return err
+	}, h
+}
+
+// WithDeadLetter writes a letter to the dead letter once the attempts have been given up on,
+// holding the payload set by WithPayload and the history of every attempt.
+// Attempts stopped by their context being done are not written.
+func WithDeadLetter(dl DeadLetter) Option {
+	return func(r *retry) error {
+		if dl == nil {
+			return errors.New(`dead letter must not be nil`)
+		}
+		r.deadLetter = dl
+		return nil
+	}
+}
+
+// MemoryDeadLetter stores letters in memory.
+type MemoryDeadLetter struct {
+	mu      sync.Mutex
+	letters []Letter
+}
+
+var _ DeadLetter = (*MemoryDeadLetter)(nil)
+
+// NewMemoryDeadLetter creates an empty in memory dead letter.
+func NewMemoryDeadLetter() *MemoryDeadLetter {
+	return &MemoryDeadLetter{}
+}
+
+// Put implements DeadLetter
+func (m *MemoryDeadLetter) Put(_ context.Context, l Letter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.letters = append(m.letters, l)
+	return nil
+}
+
+// List implements DeadLetter
+func (m *MemoryDeadLetter) List(_ context.Context) ([]Letter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Letter(nil), m.letters...), nil
+}
+
+// Remove implements DeadLetter
+func (m *MemoryDeadLetter) Remove(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, l := range m.letters {
+		if l.ID == id {
+			m.letters = append(m.letters[:i], m.letters[i+1:]...)
+			return nil
+		}
+	}
+	return ErrLetterNotFound
+}
+
+type deadLetterRecord struct {
+	Letter *Letter `json:"letter,omitempty"`
+	Remove string  `json:"remove,omitempty"`
+}
+
+// deadLetterCompactThreshold is the number of records kept in the file
+// for each stored letter before the file is compacted.
+const deadLetterCompactThreshold = 4
+
+// FileDeadLetter stores letters in an append only file, so they are kept between restarts.
+// The file is compacted once it mostly holds letters that have since been removed.
+type FileDeadLetter struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	records int
+	letters map[string]Letter
+}
+
+var _ DeadLetter = (*FileDeadLetter)(nil)
+
+// OpenFileDeadLetter loads the letters stored in the file at path,
+// creating the file if it does not exist.
+func OpenFileDeadLetter(path string) (*FileDeadLetter, error) {
+	letters := make(map[string]Letter)
+	f, records, err := journal.Open(path, func(line []byte) error {
+		var rec deadLetterRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return err
+		}
+		if rec.Letter != nil {
+			letters[rec.Letter.ID] = *rec.Letter
+		}
+		if rec.Remove != "" {
+			delete(letters, rec.Remove)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &FileDeadLetter{path: path, file: f, records: records, letters: letters}, nil
+}
+
+func (fd *FileDeadLetter) append(rec deadLetterRecord) error {
+	if err := journal.Append(fd.file, rec); err != nil {
+		return err
+	}
+	fd.records++
+	return nil
+}
+
+// Compact rewrites the file to only hold the letters that are still stored.
+func (fd *FileDeadLetter) Compact() error {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	return fd.compact()
+}
+
+func (fd *FileDeadLetter) compact() error {
+	records := make([]interface{}, 0, len(fd.letters))
+	for _, l := range fd.letters {
+		l := l
+		records = append(records, deadLetterRecord{Letter: &l})
+	}
+	f, err := journal.Rewrite(fd.path, records)
+	if err != nil {
+		return err
+	}
+	fd.file.Close()
+	fd.file, fd.records = f, len(records)
+	return nil
+}
+
+// Put implements DeadLetter
+func (fd *FileDeadLetter) Put(_ context.Context, l Letter) error {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	if err := fd.append(deadLetterRecord{Letter: &l}); err != nil {
+		return err
+	}
+	fd.letters[l.ID] = l
+	return nil
+}
+
+// List implements DeadLetter
+func (fd *FileDeadLetter) List(_ context.Context) ([]Letter, error) {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	letters := make([]Letter, 0, len(fd.letters))
+	for _, l := range fd.letters {
+		letters = append(letters, l)
+	}
+	sort.SliceStable(letters, func(i, j int) bool {
+		return letters[i].Time.Before(letters[j].Time)
+	})
+	return letters, nil
+}
+
+// Remove implements DeadLetter
+func (fd *FileDeadLetter) Remove(_ context.Context, id string) error {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	if _, exist := fd.letters[id]; !exist {
+		return ErrLetterNotFound
+	}
+	if err := fd.append(deadLetterRecord{Remove: id}); err != nil {
+		return err
+	}
+	delete(fd.letters, id)
+	if fd.records > deadLetterCompactThreshold*(len(fd.letters)+1) {
+		// The letter has already been removed, so a failed compaction
+		// only leaves the file larger until the next one
+		_ = fd.compact()
+	}
+	return nil
+}
+
+// Close closes the underlying file.
+func (fd *FileDeadLetter) Close() error {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	return fd.file.Close()
+}
@@ -0,0 +1,157 @@
+package retry_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+func TestInvalidDeadLetter(t *testing.T) {
+	t.Parallel()
+
+	_, err := retry.New(retry.WithDeadLetter(nil))
+	assert.Error(t, err, `Must not allow a nil dead letter`)
+
+	_, err = retry.Redrive(context.Background(), nil, func(context.Context, retry.Letter) error { return nil })
+	assert.Error(t, err, `Must not allow a nil dead letter`)
+
+	_, err = retry.Redrive(context.Background(), retry.NewMemoryDeadLetter(), nil)
+	assert.Error(t, err, `Must not allow a nil submit function`)
+}
+
+func TestDeadLetterRecordsHistory(t *testing.T) {
+	t.Parallel()
+
+	dl := retry.NewMemoryDeadLetter()
+	r := retry.Must(retry.WithDeadLetter(dl))
+
+	called := 0
+	err := r.DoWithContext(retry.WithPayload(context.Background(), []byte(`payload`)), 3, func() error {
+		called++
+		return errors.New(`discard`)
+	})
+	assert.True(t, retry.HasExceeded(err))
+
+	letters, err := dl.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, []byte(`payload`), letters[0].Payload)
+	assert.Contains(t, letters[0].Error, `exceeded attempts`)
+	require.Len(t, letters[0].Attempts, 3, `Must record every attempt`)
+	for i, a := range letters[0].Attempts {
+		assert.Equal(t, i+1, a.Number)
+		assert.Equal(t, `discard`, a.Error)
+	}
+
+	require.NoError(t, r.Do(1, func() error { return nil }))
+	ctx, cancel := context.WithCancel(context.Background())
+	_ = r.DoWithContext(ctx, 2, func() error {
+		cancel()
+		return errors.New(`discard`)
+	})
+	letters, err = dl.List(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, letters, 1, `Successful and cancelled attempts must not be written`)
+
+	err = r.DoWithContext(retry.WithoutDeadLetter(context.Background()), 1, func() error {
+		return errors.New(`discard`)
+	})
+	assert.True(t, retry.HasExceeded(err))
+	letters, err = dl.List(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, letters, 1, `Attempts made without the dead letter must not be written`)
+}
+
+func TestRedrive(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "deadletter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "letters")
+
+	fd, err := retry.OpenFileDeadLetter(path)
+	require.NoError(t, err)
+	for _, payload := range []string{`first`, `second`, `third`} {
+		require.NoError(t, fd.Put(context.Background(), retry.NewLetter([]byte(payload), errors.New(`boom`), nil)))
+		// Ensure each letter has a distinct time to be ordered by
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, retry.ErrLetterNotFound, fd.Remove(context.Background(), `missing`))
+	require.NoError(t, fd.Close())
+
+	fd, err = retry.OpenFileDeadLetter(path)
+	require.NoError(t, err)
+	defer fd.Close()
+
+	var submitted []string
+	n, err := retry.Redrive(context.Background(), fd, func(_ context.Context, l retry.Letter) error {
+		if string(l.Payload) == `third` {
+			return errors.New(`unavailable`)
+		}
+		submitted = append(submitted, string(l.Payload))
+		return nil
+	})
+	assert.EqualError(t, err, `unavailable`)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{`first`, `second`}, submitted, `Must redrive the oldest letters first`)
+
+	letters, err := fd.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, letters, 1, `Redriven letters must be removed`)
+	assert.Equal(t, []byte(`third`), letters[0].Payload)
+
+	mem := retry.NewMemoryDeadLetter()
+	require.NoError(t, mem.Put(context.Background(), letters[0]))
+	n, err = retry.Redrive(context.Background(), mem, func(context.Context, retry.Letter) error { return nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, retry.ErrLetterNotFound, mem.Remove(context.Background(), letters[0].ID))
+}
+
+func TestFileDeadLetterCompaction(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "deadletter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "letters")
+
+	fd, err := retry.OpenFileDeadLetter(path)
+	require.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		require.NoError(t, fd.Put(context.Background(), retry.NewLetter([]byte(`payload`), errors.New(`boom`), nil)))
+	}
+	n, err := retry.Redrive(context.Background(), fd, func(context.Context, retry.Letter) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, 20, n)
+
+	kept := retry.NewLetter([]byte(`kept`), errors.New(`boom`), nil)
+	require.NoError(t, fd.Put(context.Background(), kept))
+	require.NoError(t, fd.Compact())
+	require.NoError(t, fd.Close())
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, bytes.Count(data, []byte("\n")), `Compacted file must only hold stored letters`)
+
+	fd, err = retry.OpenFileDeadLetter(path)
+	require.NoError(t, err)
+	defer fd.Close()
+
+	letters, err := fd.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, letters, 1, `Must keep the stored letters`)
+	assert.Equal(t, kept.ID, letters[0].ID)
+	assert.Equal(t, []byte(`kept`), letters[0].Payload)
+}
@@ -59,3 +59,111 @@ func HasAborted(err error) bool {
 	}
 	return errors.As(err, &abort{})
 }
+
+type exhausted struct {
+	err error
+}
+
+func (e exhausted) Error() string {
+	return fmt.Sprintf("exhausted retry budget: %v", e.err)
+}
+
+func (e exhausted) Unwrap() error {
+	return e.err
+}
+
+// ExhaustedBudget wraps the last error returned before the retry budget
+// ran out of tokens to spend on another attempt.
+func ExhaustedBudget(err error) error {
+	return exhausted{err: err}
+}
+
+// HasExhaustedBudget checks the error to validate
+// if the retries were stopped by an empty retry budget.
+func HasExhaustedBudget(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.As(err, &exhausted{})
+}
+
+type suppressed struct {
+	err error
+}
+
+func (s suppressed) Error() string {
+	return fmt.Sprintf("suppressed retries: %v", s.err)
+}
+
+func (s suppressed) Unwrap() error {
+	return s.err
+}
+
+// SuppressedRetries wraps the last error returned before retries
+// were disabled to prevent a retry storm.
+func SuppressedRetries(err error) error {
+	return suppressed{err: err}
+}
+
+// HasSuppressed checks the error to validate
+// if the retries were stopped by a retry storm detector.
+func HasSuppressed(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.As(err, &suppressed{})
+}
+
+type rejected struct {
+	err error
+}
+
+func (r rejected) Error() string {
+	return fmt.Sprintf("rejected attempt: %v", r.err)
+}
+
+func (r rejected) Unwrap() error {
+	return r.err
+}
+
+// RejectedAttempt wraps the reason an attempt
+// was not allowed to start by a concurrency limit.
+func RejectedAttempt(err error) error {
+	return rejected{err: err}
+}
+
+// HasRejected checks the error to validate
+// if the attempt was rejected by a concurrency limit.
+func HasRejected(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.As(err, &rejected{})
+}
+
+type fellBack struct {
+	err error
+}
+
+func (f fellBack) Error() string {
+	return fmt.Sprintf("fallback used: %v", f.err)
+}
+
+func (f fellBack) Unwrap() error {
+	return f.err
+}
+
+// FellBack wraps the final error of the attempts
+// that were replaced by a successful fallback.
+func FellBack(err error) error {
+	return fellBack{err: err}
+}
+
+// HasFallenBack checks the error to validate
+// if the result was provided by the fallback.
+func HasFallenBack(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.As(err, &fellBack{})
+}
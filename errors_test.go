@@ -27,6 +27,19 @@ func TestWrappedErrors(t *testing.T) {
 		}, msg: `Ensures an exceeded error can not validate as an abort error`},
 		{err: nil, is: func(err error) bool { return !retry.HasAborted(err) }, msg: `Ensure that nil does not resolve as an abort`},
 		{err: nil, is: func(err error) bool { return !retry.HasExceeded(err) }, msg: `Ensure that nil does not resolve as an exceeded`},
+		{err: retry.ExhaustedBudget(errors.New(`no tokens`)), is: retry.HasExhaustedBudget, msg: `Checks to see if an exhausted budget error correctly validates`},
+		{err: retry.ExhaustedBudget(errors.New(`no tokens`)), is: func(err error) bool {
+			return !retry.HasExceeded(err) && !retry.HasAborted(err)
+		}, msg: `Ensures an exhausted budget error can not validate as other errors`},
+		{err: nil, is: func(err error) bool { return !retry.HasExhaustedBudget(err) }, msg: `Ensure that nil does not resolve as an exhausted budget`},
+		{err: retry.SuppressedRetries(errors.New(`storm`)), is: retry.HasSuppressed, msg: `Checks to see if a suppressed error correctly validates`},
+		{err: nil, is: func(err error) bool { return !retry.HasSuppressed(err) }, msg: `Ensure that nil does not resolve as a suppressed`},
+		{err: retry.RejectedAttempt(errors.New(`full`)), is: retry.HasRejected, msg: `Checks to see if a rejected error correctly validates`},
+		{err: nil, is: func(err error) bool { return !retry.HasRejected(err) }, msg: `Ensure that nil does not resolve as a rejected`},
+		{err: retry.FellBack(retry.ExceededRetries(errors.New(`boom`))), is: func(err error) bool {
+			return retry.HasFallenBack(err) && retry.HasExceeded(err)
+		}, msg: `Checks to see if a fallback error correctly validates and keeps the final error`},
+		{err: nil, is: func(err error) bool { return !retry.HasFallenBack(err) }, msg: `Ensure that nil does not resolve as a fallback`},
 	}
 
 	for _, test := range tests {
@@ -39,4 +52,8 @@ func TestErrorPrinting(t *testing.T) {
 
 	assert.Contains(t, retry.AbortedRetries(errors.New("")).Error(), `aborted retries:`)
 	assert.Contains(t, retry.ExceededRetries(errors.New("")).Error(), `exceeded attempts:`)
+	assert.Contains(t, retry.ExhaustedBudget(errors.New("")).Error(), `exhausted retry budget:`)
+	assert.Contains(t, retry.SuppressedRetries(errors.New("")).Error(), `suppressed retries:`)
+	assert.Contains(t, retry.RejectedAttempt(errors.New("")).Error(), `rejected attempt:`)
+	assert.Contains(t, retry.FellBack(errors.New("")).Error(), `fallback used:`)
 }
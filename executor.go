@@ -0,0 +1,231 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrExecutorShutdown is returned when a task is submitted to, or has not been
+// started by, an Executor that is shutting down.
+var ErrExecutorShutdown = errors.New(`executor has been shutdown`)
+
+// Task is a unit of work run by an Executor.
+type Task struct {
+	// Func is attempted until it succeeds or the attempts are given up on.
+	Func func(ctx context.Context) error
+	// Attempts overrides the limit of attempts set on the Executor when positive.
+	Attempts int
+	// Retryer overrides the Retryer set on the Executor when not nil.
+	Retryer Retryer
+	// Done is optionally called with the final result of the task.
+	Done func(err error)
+}
+
+// ExecutorStats are the aggregate counts of the tasks run by an Executor.
+type ExecutorStats struct {
+	Succeeded uint64
+	Failed    uint64
+	// Retried is the number of attempts made after the first attempt of each task.
+	Retried uint64
+}
+
+// Executor runs submitted tasks on a fixed number of workers, each task being
+// retried with the policy of its Retryer.
+type Executor struct {
+	// Counters are kept first to ensure 64 bit alignment for atomic access
+	succeeded uint64
+	failed    uint64
+	retried   uint64
+
+	retryer  Retryer
+	attempts int
+
+	mu       sync.Mutex
+	closed   bool
+	queue    chan Task
+	stopping chan struct{}
+	wg       sync.WaitGroup
+	// submitting tracks the Submit calls that may still send to the queue,
+	// so shutdown only drains the queue once none of them can add to it.
+	submitting sync.WaitGroup
+
+	// retries is cancelled on shutdown to stop any further attempts,
+	// where as running is only cancelled if shutdown is not able to wait.
+	retries context.Context
+	stop    context.CancelFunc
+	running context.Context
+	kill    context.CancelFunc
+}
+
+// NewExecutor creates a running executor with the number of workers, that holds up to
+// queue submitted tasks waiting to start, with each task attempted up to attempts times.
+func NewExecutor(r Retryer, attempts, workers, queue int) (*Executor, error) {
+	if r == nil {
+		return nil, errors.New(`retryer is nil`)
+	}
+	if attempts < 1 {
+		return nil, errors.New(`attempts must be positive`)
+	}
+	if workers < 1 {
+		return nil, errors.New(`workers must be positive`)
+	}
+	if queue < 0 {
+		return nil, errors.New(`queue must not be negative`)
+	}
+	e := &Executor{
+		retryer:  r,
+		attempts: attempts,
+		queue:    make(chan Task, queue),
+		stopping: make(chan struct{}),
+	}
+	e.retries, e.stop = context.WithCancel(context.Background())
+	e.running, e.kill = context.WithCancel(context.Background())
+
+	e.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go e.worker()
+	}
+	return e, nil
+}
+
+// Submit queues the task to be run, blocking while the queue is full
+// until there is space or the context is done.
+func (e *Executor) Submit(ctx context.Context, t Task) error {
+	if t.Func == nil {
+		return errors.New(`invalid function provided`)
+	}
+
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return ErrExecutorShutdown
+	}
+	e.submitting.Add(1)
+	e.mu.Unlock()
+	defer e.submitting.Done()
+
+	select {
+	case e.queue <- t:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-e.stopping:
+		return ErrExecutorShutdown
+	}
+}
+
+// Shutdown stops accepting tasks and stops any further retries from being started,
+// then waits for the attempts in flight to finish. Tasks that had not yet started are
+// failed with ErrExecutorShutdown. If the context is done before the attempts in flight
+// finish, their contexts are cancelled and the context error is returned.
+func (e *Executor) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return ErrExecutorShutdown
+	}
+	e.closed = true
+	close(e.stopping)
+	e.mu.Unlock()
+
+	e.stop()
+
+	finished := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(finished)
+	}()
+
+	var err error
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		e.kill()
+		<-finished
+		err = ctx.Err()
+	}
+
+	// Any blocked submits return once stopping is closed,
+	// but may have queued their task before noticing.
+	e.submitting.Wait()
+	for {
+		select {
+		case t := <-e.queue:
+			e.complete(t, ErrExecutorShutdown)
+		default:
+			e.kill()
+			return err
+		}
+	}
+}
+
+// Stats returns the aggregate counts of the tasks run so far.
+func (e *Executor) Stats() ExecutorStats {
+	return ExecutorStats{
+		Succeeded: atomic.LoadUint64(&e.succeeded),
+		Failed:    atomic.LoadUint64(&e.failed),
+		Retried:   atomic.LoadUint64(&e.retried),
+	}
+}
+
+func (e *Executor) worker() {
+	defer e.wg.Done()
+	for {
+		// Prioritise stopping over starting any queued tasks
+		select {
+		case <-e.stopping:
+			return
+		default:
+		}
+
+		select {
+		case t := <-e.queue:
+			e.run(t)
+		case <-e.stopping:
+			return
+		}
+	}
+}
+
+func (e *Executor) run(t Task) {
+	r, attempts := e.retryer, e.attempts
+	if t.Retryer != nil {
+		r = t.Retryer
+	}
+	if t.Attempts > 0 {
+		attempts = t.Attempts
+	}
+
+	if e.retries.Err() != nil {
+		e.complete(t, ErrExecutorShutdown)
+		return
+	}
+
+	// Counted atomically since a hedging Retryer makes attempts concurrently
+	var attempt int64
+	// Attempts are given the running context rather than the one passed in,
+	// so attempts in flight are not cancelled when retries are stopped.
+	err := DoWithContextFunc(e.retries, r, attempts, func(context.Context) error {
+		if atomic.AddInt64(&attempt, 1) > 1 {
+			atomic.AddUint64(&e.retried, 1)
+		}
+		return t.Func(e.running)
+	})
+	if err != nil && e.retries.Err() != nil && errors.Is(err, e.retries.Err()) {
+		err = ErrExecutorShutdown
+	}
+	e.complete(t, err)
+}
+
+func (e *Executor) complete(t Task, err error) {
+	if err == nil {
+		atomic.AddUint64(&e.succeeded, 1)
+	} else {
+		atomic.AddUint64(&e.failed, 1)
+	}
+	if t.Done != nil {
+		t.Done(err)
+	}
+}
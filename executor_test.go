@@ -0,0 +1,217 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+func TestInvalidExecutor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		r                        retry.Retryer
+		attempts, workers, queue int
+		msg                      string
+	}{
+		{r: nil, attempts: 1, workers: 1, queue: 1, msg: `Must not allow a nil retryer`},
+		{r: retry.Must(), attempts: 0, workers: 1, queue: 1, msg: `Must not allow non positive attempts`},
+		{r: retry.Must(), attempts: 1, workers: 0, queue: 1, msg: `Must not allow non positive workers`},
+		{r: retry.Must(), attempts: 1, workers: 1, queue: -1, msg: `Must not allow a negative queue`},
+	}
+	for _, test := range tests {
+		_, err := retry.NewExecutor(test.r, test.attempts, test.workers, test.queue)
+		assert.Error(t, err, test.msg)
+	}
+
+	e, err := retry.NewExecutor(retry.Must(), 1, 1, 0)
+	require.NoError(t, err)
+	assert.Error(t, e.Submit(context.Background(), retry.Task{}), `Must not allow a task without a function`)
+	require.NoError(t, e.Shutdown(context.Background()))
+	assert.Equal(t, retry.ErrExecutorShutdown, e.Shutdown(context.Background()))
+	assert.Equal(t, retry.ErrExecutorShutdown, e.Submit(context.Background(), retry.Task{Func: func(context.Context) error { return nil }}))
+}
+
+func TestExecutorRunsTasks(t *testing.T) {
+	t.Parallel()
+
+	e, err := retry.NewExecutor(retry.Must(), 3, 4, 8)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		called, fail := 0, i%4 == 0
+		wg.Add(1)
+		require.NoError(t, e.Submit(context.Background(), retry.Task{
+			Func: func(context.Context) error {
+				if called++; fail || called < 2 {
+					return errors.New(`discard`)
+				}
+				return nil
+			},
+			Done: func(error) { wg.Done() },
+		}))
+	}
+
+	wg.Add(1)
+	require.NoError(t, e.Submit(context.Background(), retry.Task{
+		Func:     func(context.Context) error { return errors.New(`discard`) },
+		Attempts: 1,
+		Done: func(err error) {
+			defer wg.Done()
+			assert.True(t, retry.HasExceeded(err))
+		},
+	}))
+	wg.Wait()
+
+	assert.Equal(t, retry.ExecutorStats{Succeeded: 15, Failed: 6, Retried: 25}, e.Stats())
+	require.NoError(t, e.Shutdown(context.Background()))
+}
+
+func TestExecutorBackpressure(t *testing.T) {
+	t.Parallel()
+
+	e, err := retry.NewExecutor(retry.Must(), 1, 1, 1)
+	require.NoError(t, err)
+
+	hold := make(chan struct{})
+	started := make(chan struct{})
+	block := retry.Task{Func: func(context.Context) error {
+		started <- struct{}{}
+		<-hold
+		return nil
+	}}
+	require.NoError(t, e.Submit(context.Background(), block))
+	<-started
+	require.NoError(t, e.Submit(context.Background(), block), `Queue must hold one task`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, e.Submit(ctx, block), `Must block while the queue is full`)
+
+	close(hold)
+	<-started
+	require.NoError(t, e.Shutdown(context.Background()))
+}
+
+func TestExecutorGracefulShutdown(t *testing.T) {
+	t.Parallel()
+
+	e, err := retry.NewExecutor(retry.Must(retry.WithFixedDelay(time.Hour)), 3, 1, 4)
+	require.NoError(t, err)
+
+	var (
+		called   int64
+		results  = make(chan error, 2)
+		started  = make(chan struct{})
+		inflight = make(chan struct{})
+	)
+	require.NoError(t, e.Submit(context.Background(), retry.Task{
+		Func: func(ctx context.Context) error {
+			atomic.AddInt64(&called, 1)
+			close(started)
+			<-inflight
+			assert.NoError(t, ctx.Err(), `Attempts in flight must not be cancelled`)
+			return errors.New(`discard`)
+		},
+		Done: func(err error) { results <- err },
+	}))
+	require.NoError(t, e.Submit(context.Background(), retry.Task{
+		Func: func(context.Context) error { return nil },
+		Done: func(err error) { results <- err },
+	}))
+	<-started
+
+	shutdown := make(chan error)
+	go func() { shutdown <- e.Shutdown(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	close(inflight)
+
+	require.NoError(t, <-shutdown)
+	assert.Equal(t, retry.ErrExecutorShutdown, <-results, `Must not retry once shutdown`)
+	assert.Equal(t, retry.ErrExecutorShutdown, <-results, `Queued tasks must not be started`)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&called))
+	assert.Equal(t, retry.ExecutorStats{Failed: 2}, e.Stats())
+}
+
+func TestExecutorShutdownWithBlockedSubmit(t *testing.T) {
+	t.Parallel()
+
+	e, err := retry.NewExecutor(retry.Must(), 1, 1, 0)
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	require.NoError(t, e.Submit(context.Background(), retry.Task{
+		Func: func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}))
+	<-started
+
+	submitted := make(chan error)
+	go func() {
+		submitted <- e.Submit(context.Background(), retry.Task{
+			Func: func(context.Context) error { return nil },
+		})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	assert.Equal(t, context.DeadlineExceeded, e.Shutdown(ctx), `Must stop waiting once the context is done`)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second), `Must not be blocked by a waiting submit`)
+
+	select {
+	case err := <-submitted:
+		assert.Equal(t, retry.ErrExecutorShutdown, err, `Blocked submit must be rejected`)
+	case <-time.After(time.Second):
+		assert.Fail(t, `Blocked submit must return on shutdown`)
+	}
+}
+
+func TestExecutorHedgedRetryer(t *testing.T) {
+	t.Parallel()
+
+	e, err := retry.NewExecutor(retry.Must(retry.WithHedging(time.Millisecond, 2)), 3, 2, 4)
+	require.NoError(t, err)
+
+	var (
+		running int64
+		wg      sync.WaitGroup
+	)
+	task := retry.Task{
+		Func: func(ctx context.Context) error {
+			atomic.AddInt64(&running, 1)
+			defer atomic.AddInt64(&running, -1)
+			time.Sleep(5 * time.Millisecond)
+			return errors.New(`discard`)
+		},
+		Done: func(error) { wg.Done() },
+	}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		require.NoError(t, e.Submit(context.Background(), task))
+	}
+	wg.Wait()
+	assert.Equal(t, retry.ExecutorStats{Failed: 4, Retried: 8}, e.Stats(), `Must count each hedged attempt`)
+
+	wg.Add(1)
+	require.NoError(t, e.Submit(context.Background(), task))
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt64(&running) > 0
+	}, time.Second, time.Millisecond)
+	require.NoError(t, e.Shutdown(context.Background()))
+	assert.Equal(t, int64(0), atomic.LoadInt64(&running), `Shutdown must wait for every hedged attempt`)
+	wg.Wait()
+}
@@ -0,0 +1,129 @@
+package retry
+
+import (
+	"context"
+	"sync"
+)
+
+// Progress is a snapshot of the attempts made so far by a Future.
+type Progress struct {
+	Attempts  int
+	LastError error
+}
+
+// Future is a handle to attempts being made in the background by DoAsync.
+type Future struct {
+	cancel context.CancelFunc
+	// stop is called on Cancel to remove the attempts from a Scheduler
+	stop func()
+	done chan struct{}
+	once sync.Once
+	err  error
+
+	mu       sync.Mutex
+	progress Progress
+}
+
+func newFuture(cancel context.CancelFunc) *Future {
+	return &Future{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+}
+
+// track wraps f so that each attempt is recorded in the progress of the future.
+func (fu *Future) track(f func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		fu.mu.Lock()
+		fu.progress.Attempts++
+		fu.mu.Unlock()
+
+		err := f(ctx)
+
+		fu.mu.Lock()
+		fu.progress.LastError = err
+		fu.mu.Unlock()
+		return err
+	}
+}
+
+func (fu *Future) complete(err error) {
+	fu.once.Do(func() {
+		fu.err = err
+		fu.cancel()
+		close(fu.done)
+	})
+}
+
+// Done returns a channel that is closed once the attempts have finished.
+func (fu *Future) Done() <-chan struct{} {
+	return fu.done
+}
+
+// Wait blocks until the attempts have finished and returns their result,
+// or returns the context error if the context is done first.
+func (fu *Future) Wait(ctx context.Context) error {
+	select {
+	case <-fu.done:
+		return fu.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Cancel stops any further attempts from being made,
+// including interrupting any delay between attempts.
+func (fu *Future) Cancel() {
+	fu.cancel()
+	if fu.stop != nil {
+		fu.stop()
+	}
+}
+
+// Progress returns a snapshot of the attempts made so far.
+func (fu *Future) Progress() Progress {
+	fu.mu.Lock()
+	defer fu.mu.Unlock()
+	return fu.progress
+}
+
+// DoAsync runs DoWithContextFunc in the background, returning a Future
+// that can be used to wait on, cancel or check the progress of the attempts.
+// A Retryer that does not implement DoAsync runs the attempts in a new goroutine.
+func DoAsync(ctx context.Context, r Retryer, limit int, f func(ctx context.Context) error) *Future {
+	if ra, ok := r.(interface {
+		DoAsync(ctx context.Context, limit int, f func(ctx context.Context) error) *Future
+	}); ok {
+		return ra.DoAsync(ctx, limit, f)
+	}
+	if err := validate(ctx, f); err != nil {
+		fu := newFuture(func() {})
+		fu.complete(err)
+		return fu
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	fu := newFuture(cancel)
+	go func() {
+		fu.complete(DoWithContextFunc(ctx, r, limit, fu.track(f)))
+	}()
+	return fu
+}
+
+func (r *retry) DoAsync(ctx context.Context, limit int, f func(ctx context.Context) error) *Future {
+	if err := validate(ctx, f); err != nil {
+		fu := newFuture(func() {})
+		fu.complete(err)
+		return fu
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	fu := newFuture(cancel)
+	if r.scheduler != nil && r.hedges == 0 {
+		ctx, f, h := r.record(ctx, fu.track(f))
+		r.scheduler.submit(&task{r: r, ctx: ctx, f: f, limit: limit, future: fu, history: h})
+		return fu
+	}
+	go func() {
+		fu.complete(r.do(ctx, limit, fu.track(f)))
+	}()
+	return fu
+}
@@ -0,0 +1,65 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+func TestDoAsync(t *testing.T) {
+	t.Parallel()
+
+	called := 0
+	fu := retry.DoAsync(context.Background(), retry.Must(), 3, func(context.Context) error {
+		if called++; called < 3 {
+			return errors.New(`discard`)
+		}
+		return nil
+	})
+	assert.NoError(t, fu.Wait(context.Background()))
+	assert.Equal(t, retry.Progress{Attempts: 3}, fu.Progress())
+
+	select {
+	case <-fu.Done():
+	default:
+		assert.Fail(t, `Done must be closed once finished`)
+	}
+
+	fu = retry.DoAsync(nil, retry.Must(), 1, func(context.Context) error { return nil })
+	assert.Error(t, fu.Wait(context.Background()), `Must fail with an invalid context`)
+
+	fu = retry.DoAsync(context.Background(), retry.Must(), 1, nil)
+	assert.Error(t, fu.Wait(context.Background()), `Must fail with an invalid function`)
+}
+
+func TestDoAsyncCancelDuringDelay(t *testing.T) {
+	t.Parallel()
+
+	attempted := make(chan struct{})
+	fu := retry.DoAsync(context.Background(), retry.Must(retry.WithFixedDelay(time.Hour)), 3, func(context.Context) error {
+		close(attempted)
+		return errors.New(`discard`)
+	})
+	<-attempted
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, fu.Wait(ctx), `Wait must return once the context is done`)
+
+	fu.Cancel()
+	select {
+	case <-fu.Done():
+	case <-time.After(time.Second):
+		assert.Fail(t, `Cancel must interrupt the delay between attempts`)
+	}
+	assert.Equal(t, context.Canceled, fu.Wait(context.Background()))
+
+	progress := fu.Progress()
+	assert.Equal(t, 1, progress.Attempts)
+	assert.EqualError(t, progress.LastError, `discard`)
+}
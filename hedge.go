@@ -0,0 +1,147 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// WithHedging starts another attempt whenever the latest attempt has not returned
+// within the delay, making up to hedges additional attempts while earlier ones are
+// still running. The first attempt to succeed is returned, and the context passed
+// through DoWithContextFunc to every other attempt is cancelled.
+// The call returns once every attempt has returned, so attempts should stop once cancelled.
+// The attempts that fail after being cancelled are not reported to any observers,
+// such as an AIMD, and are reported to a CircuitBreaker as context.Canceled.
+// Hedged attempts count towards the limit and any permits, such as a RetryBudget.
+func WithHedging(delay time.Duration, hedges int) Option {
+	return func(r *retry) error {
+		if delay <= 0 {
+			return errors.New(`delay must be a positive value`)
+		}
+		if hedges < 1 {
+			return errors.New(`hedges must be positive`)
+		}
+		r.hedgeDelay, r.hedges = delay, hedges
+		return nil
+	}
+}
+
+// hedgeKey holds a channel that is closed once the hedged attempts have a result,
+// so any attempts still running are known to have been abandoned.
+type hedgeKey struct{}
+
+// abandoned reports if the attempt failed after it was no longer needed, such as a hedged attempt
+// that was beaten by another, which says nothing about the health of what was attempted.
+func abandoned(ctx context.Context, err error) bool {
+	settled, ok := ctx.Value(hedgeKey{}).(chan struct{})
+	if err == nil || !ok {
+		return false
+	}
+	select {
+	case <-settled:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *retry) hedged(parent context.Context, limit int, f func(ctx context.Context) error) error {
+	// Waiting on return ensures no attempt is still running once the call returns,
+	// which runs after the attempts have been cancelled below
+	var running sync.WaitGroup
+	defer running.Wait()
+	ctx, cancel := context.WithCancel(parent)
+	// Cancelling on return ensures any attempts still running are stopped
+	defer cancel()
+	settled := make(chan struct{})
+	ctx = context.WithValue(ctx, hedgeKey{}, settled)
+	// Closed before cancelling so the attempts that are stopped are known to be abandoned
+	defer close(settled)
+
+	err := errors.New(`exceeded allowed attempts`)
+	if limit < 1 {
+		// Matches the attempts made without hedging,
+		// which assume all attempts have been exceeded
+		return ExceededRetries(err)
+	}
+
+	var (
+		results  = make(chan error, limit)
+		denied   error
+		launched int
+		inflight int
+		hedges   int
+	)
+	// The hedge timer is restarted each time an attempt is launched,
+	// so the delay is always measured from the latest attempt.
+	hedge := time.NewTimer(r.hedgeDelay)
+	defer hedge.Stop()
+
+	start := func() {
+		launched++
+		inflight++
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			results <- r.attempt(ctx, f)
+		}()
+		if !hedge.Stop() {
+			select {
+			case <-hedge.C:
+			default:
+			}
+		}
+		hedge.Reset(r.hedgeDelay)
+	}
+	launch := func() {
+		if _, denied = r.permit(ctx, launched+1, err); denied == nil {
+			start()
+		}
+	}
+
+	if launched < limit {
+		launch()
+	}
+	for inflight > 0 {
+		var wait <-chan time.Time
+		if denied == nil && hedges < r.hedges && launched < limit {
+			wait = hedge.C
+		}
+
+		select {
+		case <-parent.Done():
+			return parent.Err()
+		case <-wait:
+			hedges++
+			launch()
+		case err = <-results:
+			inflight--
+			if err == nil {
+				return nil
+			}
+			if aerr := r.abort(err); aerr != nil {
+				return aerr
+			}
+			if inflight == 0 && denied == nil && launched < limit {
+				// The retry is permitted before waiting,
+				// so one that is not permitted fails straight away
+				var refund func()
+				if refund, denied = r.permit(ctx, launched+1, err); denied != nil {
+					break
+				}
+				if !sleep(parent, r.backoff(limit-launched+1, limit)) {
+					refund()
+					return parent.Err()
+				}
+				start()
+			}
+		}
+	}
+	if denied != nil {
+		return denied
+	}
+	// Returns the last error recorded
+	return ExceededRetries(err)
+}
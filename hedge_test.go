@@ -0,0 +1,180 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+func TestInvalidHedging(t *testing.T) {
+	t.Parallel()
+
+	_, err := retry.New(retry.WithHedging(0, 1))
+	assert.Error(t, err, `Must not allow a non positive delay`)
+
+	_, err = retry.New(retry.WithHedging(time.Second, 0))
+	assert.Error(t, err, `Must not allow non positive hedges`)
+}
+
+func TestHedgingNonPositiveLimit(t *testing.T) {
+	t.Parallel()
+
+	r := retry.Must(retry.WithHedging(time.Millisecond, 1))
+	for _, limit := range []int{0, -1} {
+		called := false
+		err := r.Do(limit, func() error {
+			called = true
+			return nil
+		})
+		assert.True(t, retry.HasExceeded(err), `Must exceed retries with a limit of %d`, limit)
+		assert.False(t, called, `Must not make any attempts with a limit of %d`, limit)
+	}
+}
+
+func TestHedgedAttemptWins(t *testing.T) {
+	t.Parallel()
+
+	var (
+		called    int64
+		cancelled = make(chan struct{})
+	)
+	r := retry.Must(retry.WithHedging(10*time.Millisecond, 2))
+
+	err := retry.DoWithContextFunc(context.Background(), r, 3, func(ctx context.Context) error {
+		if atomic.AddInt64(&called, 1) == 1 {
+			<-ctx.Done()
+			close(cancelled)
+			return ctx.Err()
+		}
+		return nil
+	})
+	assert.NoError(t, err, `Hedged attempt must succeed`)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&called), `Must only hedge until an attempt succeeds`)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		assert.Fail(t, `Losing attempt must be cancelled`)
+	}
+}
+
+func TestHedgingLimits(t *testing.T) {
+	t.Parallel()
+
+	var running, peak, called int64
+	r := retry.Must(retry.WithHedging(5*time.Millisecond, 1))
+
+	err := r.Do(4, func() error {
+		atomic.AddInt64(&called, 1)
+		n := atomic.AddInt64(&running, 1)
+		defer atomic.AddInt64(&running, -1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return errors.New(`discard`)
+	})
+	assert.True(t, retry.HasExceeded(err))
+	assert.Equal(t, int64(4), atomic.LoadInt64(&called), `Hedges must count towards the limit`)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&peak), `Must not exceed the max number of hedges`)
+}
+
+func TestHedgingSpendsBudget(t *testing.T) {
+	t.Parallel()
+
+	b, err := retry.NewRetryBudget(1, 0.1)
+	require.NoError(t, err)
+	r := retry.Must(retry.WithHedging(5*time.Millisecond, 3), retry.WithRetryBudget(b))
+
+	var called int64
+	err = r.Do(5, func() error {
+		atomic.AddInt64(&called, 1)
+		time.Sleep(20 * time.Millisecond)
+		return errors.New(`discard`)
+	})
+	assert.True(t, retry.HasExhaustedBudget(err), `Hedges must spend the retry budget`)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&called))
+	assert.Equal(t, 0.0, b.Tokens())
+}
+
+// countingBreaker records the result of every attempt passed through it.
+type countingBreaker struct {
+	mu      sync.Mutex
+	results []error
+}
+
+func (cb *countingBreaker) Allow() (func(err error), error) {
+	return func(err error) {
+		cb.mu.Lock()
+		defer cb.mu.Unlock()
+		cb.results = append(cb.results, err)
+	}, nil
+}
+
+func (cb *countingBreaker) recorded() []error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return append([]error(nil), cb.results...)
+}
+
+func TestHedgingAbandonedAttemptsNotReported(t *testing.T) {
+	t.Parallel()
+
+	a, err := retry.NewAIMD(time.Millisecond, time.Second, time.Millisecond, 2)
+	require.NoError(t, err)
+	cb := &countingBreaker{}
+	r := retry.Must(
+		retry.WithHedging(5*time.Millisecond, 2),
+		retry.WithCircuitBreaker(cb),
+		retry.WithAdaptiveBackoff(a),
+	)
+
+	var (
+		called int64
+		lost   = make(chan struct{})
+	)
+	err = retry.DoWithContextFunc(context.Background(), r, 3, func(ctx context.Context) error {
+		if atomic.AddInt64(&called, 1) == 1 {
+			defer close(lost)
+			<-ctx.Done()
+			return errors.New(`interrupted`)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	<-lost
+
+	assert.Eventually(t, func() bool { return len(cb.recorded()) == 2 }, time.Second, time.Millisecond)
+	assert.ElementsMatch(t, []error{nil, context.Canceled}, cb.recorded(), `Abandoned attempts must be reported as cancelled`)
+	assert.Equal(t, time.Millisecond, a.Delay(), `Abandoned attempts must not be observed as failures`)
+}
+
+func TestHedgingWaitsForAttempts(t *testing.T) {
+	t.Parallel()
+
+	var finished int64
+	r := retry.Must(retry.WithHedging(time.Millisecond, 2))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := retry.DoWithContextFunc(ctx, r, 3, func(ctx context.Context) error {
+		<-ctx.Done()
+		// Slow to stop once cancelled
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt64(&finished, 1)
+		return ctx.Err()
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, int64(3), atomic.LoadInt64(&finished), `Must not return while attempts are running`)
+}
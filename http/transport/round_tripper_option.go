@@ -25,6 +25,18 @@ func WithRetryOptions(opts ...retry.Option) Option {
 	}
 }
 
+// WithRateLimiter waits for a token from the shared rate limiter before each attempt,
+// allowing one limiter to govern every request made through a client.
+func WithRateLimiter(l *retry.RateLimiter) Option {
+	return func(cf *config) error {
+		if l == nil {
+			return errors.New(`nil rate limiter provided`)
+		}
+		cf.rtOpts = append(cf.rtOpts, retry.WithRateLimiter(l))
+		return nil
+	}
+}
+
 func WithRetryOnStatusCode(codes ...int) Option {
 	return func(c *config) error {
 		table := make(map[int]struct{})
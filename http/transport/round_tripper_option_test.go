@@ -1 +1,46 @@
 package transport_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+	"github.com/MovieStoreGuy/retry/http/client"
+	"github.com/MovieStoreGuy/retry/http/transport"
+)
+
+func TestWithRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	_, err := transport.Default(1, transport.WithRateLimiter(nil))
+	assert.Error(t, err, `Must not allow a nil rate limiter`)
+
+	var called int64
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&called, 1)
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	l, err := retry.NewRateLimiter(100, 1)
+	require.NoError(t, err)
+
+	c, err := client.Default(3,
+		transport.WithRetryOnStatusCode(http.StatusServiceUnavailable),
+		transport.WithRateLimiter(l),
+	)
+	require.NoError(t, err)
+
+	start := time.Now()
+	resp, err := c.Get(s.URL)
+	assert.Error(t, err)
+	assert.Nil(t, resp)
+	assert.Equal(t, int64(3), atomic.LoadInt64(&called))
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(20*time.Millisecond), `Attempts must be limited by the shared rate limiter`)
+}
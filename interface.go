@@ -17,3 +17,13 @@ type Retryer interface {
 	// aborted if the passed context is done.
 	DoWithContext(ctx context.Context, limit int, f func() error) error
 }
+
+// CircuitBreaker guards each attempt made by a Retryer so that calls
+// to a failing dependency can fail fast instead of being retried.
+type CircuitBreaker interface {
+	// Allow checks if an attempt is permitted to start, returning an error
+	// if it is not. Otherwise, done must be called with the result of the attempt.
+	// Attempts that were abandoned, such as hedged attempts beaten by another,
+	// are passed context.Canceled and should not be counted as a failure.
+	Allow() (done func(err error), err error)
+}
@@ -0,0 +1,111 @@
+// Package journal implements the append only files shared by the queue and the
+// file dead letter, where each record is written as a single line of JSON.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Open replays each record of the journal at path into replay, creating the file
+// if it does not exist, and returns it ready to have further records appended
+// along with the number of records that were replayed.
+func Open(path string, replay func(line []byte) error) (*os.File, int, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		records int
+		offset  int64
+		reader  = bufio.NewReader(f)
+	)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A partial line is the result of a crash during a write,
+			// it was never acknowledged so it is discarded.
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		if err := replay(line); err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		records++
+		offset += int64(len(line))
+	}
+
+	if err := f.Truncate(offset); err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, records, nil
+}
+
+// Append writes the record to the end of the journal,
+// returning once it has been synced to disk.
+func Append(f *os.File, record interface{}) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// Rewrite replaces the journal at path with one only containing the records,
+// once it has safely been written, and returns it opened to append further records.
+// The file previously opened for the journal is left for the caller to close.
+func Rewrite(path string, records []interface{}) (*os.File, error) {
+	tmp, err := os.Create(path + ".compact")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, rec := range records {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			tmp.Close()
+			return nil, err
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			tmp.Close()
+			return nil, err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, err
+	}
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+	return os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+}
@@ -0,0 +1,70 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// detached keeps the values of the context without its cancellation or deadline.
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detached) Done() <-chan struct{} { return nil }
+
+func (detached) Err() error { return nil }
+
+// flight is a shared run of attempts for a key.
+type flight struct {
+	done chan struct{}
+	err  error
+}
+
+// DoKeyed shares a single run of the attempts between all concurrent callers
+// using the same key and Retryer, with each caller receiving the shared result.
+// The shared attempts keep the values of the context of the caller that started them,
+// such as the payload set by WithPayload, but are not tied to its cancellation
+// or deadline, so a caller that stops waiting once its context is done does not cancel them.
+// A Retryer that does not implement DoKeyed runs the attempts of each caller without sharing them.
+func DoKeyed(ctx context.Context, r Retryer, key string, limit int, f func(ctx context.Context) error) error {
+	if rk, ok := r.(interface {
+		DoKeyed(ctx context.Context, key string, limit int, f func(ctx context.Context) error) error
+	}); ok {
+		return rk.DoKeyed(ctx, key, limit, f)
+	}
+	return DoWithContextFunc(ctx, r, limit, f)
+}
+
+func (r *retry) DoKeyed(ctx context.Context, key string, limit int, f func(ctx context.Context) error) error {
+	if err := validate(ctx, f); err != nil {
+		return err
+	}
+
+	r.flightsMu.Lock()
+	if r.flights == nil {
+		r.flights = make(map[string]*flight)
+	}
+	fl, exist := r.flights[key]
+	if !exist {
+		fl = &flight{done: make(chan struct{})}
+		r.flights[key] = fl
+		go func() {
+			fl.err = r.do(detached{ctx}, limit, f)
+
+			r.flightsMu.Lock()
+			delete(r.flights, key)
+			r.flightsMu.Unlock()
+			close(fl.done)
+		}()
+	}
+	r.flightsMu.Unlock()
+
+	select {
+	case <-fl.done:
+		return fl.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
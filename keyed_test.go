@@ -0,0 +1,106 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+func TestDoKeyedSharesAttempts(t *testing.T) {
+	t.Parallel()
+
+	var (
+		called  int64
+		waiting int64
+		release = make(chan struct{})
+		r       = retry.Must()
+		wg      sync.WaitGroup
+		results = make(chan error, 10)
+	)
+	refresh := func(context.Context) error {
+		if atomic.AddInt64(&called, 1) == 1 {
+			<-release
+			return errors.New(`discard`)
+		}
+		return nil
+	}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			atomic.AddInt64(&waiting, 1)
+			results <- retry.DoKeyed(context.Background(), r, `token`, 3, refresh)
+		}()
+	}
+	// Allow all callers to join the shared attempts
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt64(&waiting) == 10 && atomic.LoadInt64(&called) == 1
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for err := range results {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int64(2), atomic.LoadInt64(&called), `Callers must share the same attempts`)
+
+	assert.NoError(t, retry.DoKeyed(context.Background(), r, `token`, 1, refresh))
+	assert.Equal(t, int64(3), atomic.LoadInt64(&called), `Must start new attempts once the shared attempts finish`)
+}
+
+func TestDoKeyedAbandon(t *testing.T) {
+	t.Parallel()
+
+	var (
+		r        = retry.Must()
+		release  = make(chan struct{})
+		finished = make(chan struct{})
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := retry.DoKeyed(ctx, r, `token`, 1, func(ctx context.Context) error {
+		<-release
+		assert.NoError(t, ctx.Err(), `Shared attempts must not be cancelled by the caller`)
+		close(finished)
+		return nil
+	})
+	assert.Equal(t, context.DeadlineExceeded, err, `Caller must be able to stop waiting`)
+
+	close(release)
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		assert.Fail(t, `Shared attempts must continue once abandoned`)
+	}
+
+	assert.Error(t, retry.DoKeyed(nil, r, `token`, 1, func(context.Context) error { return nil }))
+	assert.Error(t, retry.DoKeyed(context.Background(), r, `token`, 1, nil))
+}
+
+func TestDoKeyedKeepsValues(t *testing.T) {
+	t.Parallel()
+
+	dl := retry.NewMemoryDeadLetter()
+	r := retry.Must(retry.WithDeadLetter(dl))
+
+	err := retry.DoKeyed(retry.WithPayload(context.Background(), []byte(`payload`)), r, `token`, 1, func(context.Context) error {
+		return errors.New(`discard`)
+	})
+	assert.True(t, retry.HasExceeded(err))
+
+	letters, err := dl.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, []byte(`payload`), letters[0].Payload, `Shared attempts must keep the values of the caller context`)
+}
@@ -0,0 +1,105 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned when an attempt can not be made
+// before the context is done due to the rate limit.
+var ErrRateLimited = errors.New(`rate limit would exceed context deadline`)
+
+// RateLimiter is a token bucket that caps the rate of attempts made by all
+// the Retryers, and transports, that share it across any number of goroutines.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a full bucket that allows rate attempts per second,
+// with up to burst attempts allowed at once.
+func NewRateLimiter(rate float64, burst int) (*RateLimiter, error) {
+	if rate <= 0 {
+		return nil, errors.New(`rate must be a positive value`)
+	}
+	if burst < 1 {
+		return nil, errors.New(`burst must be positive`)
+	}
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}, nil
+}
+
+// reserve takes a token from the bucket and returns
+// how long to wait until the token is available.
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens--; l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// unreserve returns a token that was reserved but never used.
+func (l *RateLimiter) unreserve() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tokens++; l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+// Wait blocks until a token is available or the context is done.
+// If the context deadline would pass before a token is available,
+// ErrRateLimited is returned straight away instead of waiting.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	wait := l.reserve()
+	if wait == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		l.unreserve()
+		return ErrRateLimited
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		l.unreserve()
+		return ctx.Err()
+	}
+}
+
+// WithRateLimiter waits for a token from the shared rate limiter before each attempt,
+// and aborts the remaining attempts if it gives up waiting.
+func WithRateLimiter(l *RateLimiter) Option {
+	return func(r *retry) error {
+		p, err := RateLimitPolicy(l)
+		if err != nil {
+			return err
+		}
+		r.interceptors = append(r.interceptors, interceptor(p))
+		return nil
+	}
+}
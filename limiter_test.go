@@ -0,0 +1,70 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+func TestInvalidRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	_, err := retry.NewRateLimiter(0, 1)
+	assert.Error(t, err, `Must not allow a non positive rate`)
+
+	_, err = retry.NewRateLimiter(1, 0)
+	assert.Error(t, err, `Must not allow a non positive burst`)
+
+	_, err = retry.New(retry.WithRateLimiter(nil))
+	assert.Error(t, err, `Must not allow a nil rate limiter`)
+}
+
+func TestRateLimiterCapsAttempts(t *testing.T) {
+	t.Parallel()
+
+	l, err := retry.NewRateLimiter(100, 2)
+	require.NoError(t, err)
+
+	start := time.Now()
+	called := 0
+	err = retry.Must(retry.WithRateLimiter(l)).Do(5, func() error {
+		called++
+		return errors.New(`discard`)
+	})
+	assert.True(t, retry.HasExceeded(err))
+	assert.Equal(t, 5, called)
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(25*time.Millisecond), `Attempts beyond the burst must wait for a token`)
+}
+
+func TestRateLimiterRespectsContext(t *testing.T) {
+	t.Parallel()
+
+	l, err := retry.NewRateLimiter(1, 1)
+	require.NoError(t, err)
+	require.NoError(t, l.Wait(context.Background()), `Must have a token within the burst`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	called := 0
+	err = retry.Must(retry.WithRateLimiter(l)).DoWithContext(ctx, 3, func() error {
+		called++
+		return nil
+	})
+	assert.True(t, retry.HasAborted(err), `Must give up waiting for a token`)
+	assert.True(t, errors.Is(err, retry.ErrRateLimited), `Must not wait past the context deadline`)
+	assert.Equal(t, 0, called)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	assert.Equal(t, context.Canceled, l.Wait(ctx), `Must stop waiting once cancelled`)
+}
@@ -1,7 +1,9 @@
 package retry
 
 import (
+	"context"
 	"errors"
+	"math"
 	"math/rand"
 	"time"
 )
@@ -16,8 +18,8 @@ func WithFixedDelay(delay time.Duration) Option {
 		if delay <= 0 {
 			return errors.New(`delay must be a positive value`)
 		}
-		r.actions = append(r.actions, func(_, _ int) {
-			time.Sleep(delay)
+		r.delays = append(r.delays, func(_, _ int) time.Duration {
+			return delay
 		})
 		return nil
 	}
@@ -30,9 +32,8 @@ func WithJitter(delay time.Duration) Option {
 		if delay <= 0 {
 			return errors.New(`delay must be a positive value`)
 		}
-		r.actions = append(r.actions, func(_, _ int) {
-			t := time.Duration(rand.Int63n(int64(delay)))
-			time.Sleep(t)
+		r.delays = append(r.delays, func(_, _ int) time.Duration {
+			return time.Duration(rand.Int63n(int64(delay)))
 		})
 		return nil
 	}
@@ -49,11 +50,117 @@ func WithExponentialBackoff(delay time.Duration, multiplier float64) Option {
 			return errors.New(`multiplier must be greater than 1.0`)
 		}
 
-		r.actions = append(r.actions, func(remaining, limit int) {
-			t := delay * time.Duration(multiplier*(float64(limit-remaining)))
-			time.Sleep(t)
+		r.delays = append(r.delays, func(remaining, limit int) time.Duration {
+			return delay * time.Duration(multiplier*(float64(limit-remaining)))
 		})
 
 		return nil
 	}
 }
+
+// WithGeometricBackoff waits delay * multiplier^(n-1) after the nth failed attempt,
+// so the first retry waits for delay and each following retry waits multiplier times longer.
+// It is commonly combined with WithMaxDelay to cap how long the delay grows.
+func WithGeometricBackoff(delay time.Duration, multiplier float64) Option {
+	return func(r *retry) error {
+		if delay <= 0 {
+			return errors.New(`delay must be a positive value`)
+		}
+		if multiplier < 1.0 {
+			return errors.New(`multiplier must be greater than 1.0`)
+		}
+
+		r.delays = append(r.delays, func(remaining, limit int) time.Duration {
+			wait := float64(delay) * math.Pow(multiplier, float64(limit-remaining))
+			if wait >= math.MaxInt64 {
+				return math.MaxInt64
+			}
+			return time.Duration(wait)
+		})
+
+		return nil
+	}
+}
+
+// WithFullJitterBackoff waits a random delay between [0, min(initial * multiplier^(n-1), max))
+// after the nth failed attempt, which is the backoff used by gRPC retry policies.
+func WithFullJitterBackoff(initial time.Duration, multiplier float64, max time.Duration) Option {
+	return func(r *retry) error {
+		if initial <= 0 {
+			return errors.New(`initial delay must be a positive value`)
+		}
+		if multiplier <= 0 {
+			return errors.New(`multiplier must be a positive value`)
+		}
+		if max <= 0 {
+			return errors.New(`max delay must be a positive value`)
+		}
+
+		r.delays = append(r.delays, func(remaining, limit int) time.Duration {
+			ceiling := float64(initial) * math.Pow(multiplier, float64(limit-remaining))
+			if ceiling > float64(max) {
+				ceiling = float64(max)
+			}
+			if ceiling < 1 {
+				return 0
+			}
+			return time.Duration(rand.Int63n(int64(ceiling)))
+		})
+
+		return nil
+	}
+}
+
+// WithMaxDelay caps the total delay experienced after each failed attempt,
+// regardless of the order it is applied with the other delay options.
+func WithMaxDelay(max time.Duration) Option {
+	return func(r *retry) error {
+		if max <= 0 {
+			return errors.New(`max delay must be a positive value`)
+		}
+		r.maxDelay = max
+		return nil
+	}
+}
+
+// WithCircuitBreaker makes each attempt through the circuit breaker,
+// any attempt rejected by the breaker is aborted and never retried.
+func WithCircuitBreaker(cb CircuitBreaker) Option {
+	return func(r *retry) error {
+		p, err := BreakerPolicy(cb)
+		if err != nil {
+			return err
+		}
+		r.interceptors = append(r.interceptors, interceptor(p))
+		return nil
+	}
+}
+
+// WithAbortOn stops any further attempts once an attempt returns an error that matches,
+// the returned error is wrapped as if the attempt had returned AbortedRetries.
+func WithAbortOn(match func(err error) bool) Option {
+	return func(r *retry) error {
+		if match == nil {
+			return errors.New(`match function must not be nil`)
+		}
+		r.aborts = append(r.aborts, match)
+		return nil
+	}
+}
+
+// WithFallback calls the fallback once the attempts have given up, passing it the final error,
+// so it can serve a cached value, a default or a degraded response instead.
+// If the fallback succeeds, the final error is wrapped by FellBack so the caller
+// can tell the result came from the fallback, otherwise the fallback error is returned.
+func WithFallback(fallback func(ctx context.Context, err error) error) Option {
+	return func(r *retry) error {
+		if fallback == nil {
+			return errors.New(`fallback must not be nil`)
+		}
+		if r.fallback != nil {
+			return errors.New(`fallback has already been set`)
+		}
+		r.fallback = fallback
+		return nil
+	}
+}
@@ -0,0 +1,113 @@
+// Package pipeline runs long multi step jobs, checkpointing the result of each step
+// so that a job which is run again resumes from the first incomplete step.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+// Step is a single step of the pipeline, which is passed the result of the previous step.
+type Step struct {
+	Name string
+	Run  func(ctx context.Context, input []byte) ([]byte, error)
+	// Attempts overrides the limit of attempts set on the pipeline when positive.
+	Attempts int
+	// Retryer overrides the Retryer set on the pipeline when not nil.
+	Retryer retry.Retryer
+}
+
+// Pipeline runs the steps in order, retrying each of them with its Retryer.
+type Pipeline struct {
+	store    Store
+	retryer  retry.Retryer
+	attempts int
+	steps    []Step
+}
+
+// New validates the steps and creates a pipeline that checkpoints to the store,
+// with each step attempted up to attempts times using the Retryer.
+func New(store Store, r retry.Retryer, attempts int, steps ...Step) (*Pipeline, error) {
+	if store == nil {
+		return nil, errors.New(`store is nil`)
+	}
+	if r == nil {
+		return nil, errors.New(`retryer is nil`)
+	}
+	if attempts < 1 {
+		return nil, errors.New(`attempts must be positive`)
+	}
+	if len(steps) == 0 {
+		return nil, errors.New(`pipeline must have at least one step`)
+	}
+	names := make(map[string]struct{}, len(steps))
+	for _, s := range steps {
+		if s.Name == "" {
+			return nil, errors.New(`step name must not be empty`)
+		}
+		if _, exist := names[s.Name]; exist {
+			return nil, fmt.Errorf("step %q already exists", s.Name)
+		}
+		names[s.Name] = struct{}{}
+		if s.Run == nil {
+			return nil, fmt.Errorf("step %q has no run function", s.Name)
+		}
+	}
+	return &Pipeline{store: store, retryer: r, attempts: attempts, steps: steps}, nil
+}
+
+// Run performs the steps of the job in order starting with the input, skipping any
+// steps that have already been completed by using their checkpointed result instead.
+// The result of the final step is returned, and the checkpoints of the job are kept
+// until they are removed from the Store with Clear.
+func (p *Pipeline) Run(ctx context.Context, job string, input []byte) ([]byte, error) {
+	for _, step := range p.steps {
+		result, done, err := p.store.Load(ctx, job, step.Name)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline step %q failed to load checkpoint: %w", step.Name, err)
+		}
+		if done {
+			input = result
+			continue
+		}
+
+		r, attempts := p.retryer, p.attempts
+		if step.Retryer != nil {
+			r = step.Retryer
+		}
+		if step.Attempts > 0 {
+			attempts = step.Attempts
+		}
+
+		var (
+			in = input
+			// mu guards the result, since a hedging Retryer makes attempts
+			// concurrently and only the first to succeed is kept
+			mu   sync.Mutex
+			kept bool
+		)
+		err = retry.DoWithContextFunc(ctx, r, attempts, func(ctx context.Context) error {
+			out, err := step.Run(ctx, in)
+			if err == nil {
+				mu.Lock()
+				if !kept {
+					result, kept = out, true
+				}
+				mu.Unlock()
+			}
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("pipeline step %q failed: %w", step.Name, err)
+		}
+		if err := p.store.Save(ctx, job, step.Name, result); err != nil {
+			return nil, fmt.Errorf("pipeline step %q failed to save checkpoint: %w", step.Name, err)
+		}
+		input = result
+	}
+	return input, nil
+}
@@ -0,0 +1,148 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+	"github.com/MovieStoreGuy/retry/pipeline"
+)
+
+func TestInvalidPipeline(t *testing.T) {
+	t.Parallel()
+
+	run := func(_ context.Context, in []byte) ([]byte, error) { return in, nil }
+	store, r := pipeline.NewMemoryStore(), retry.Must()
+
+	tests := []struct {
+		store    pipeline.Store
+		r        retry.Retryer
+		attempts int
+		steps    []pipeline.Step
+		msg      string
+	}{
+		{store: nil, r: r, attempts: 1, steps: []pipeline.Step{{Name: `a`, Run: run}}, msg: `Must not allow a nil store`},
+		{store: store, r: nil, attempts: 1, steps: []pipeline.Step{{Name: `a`, Run: run}}, msg: `Must not allow a nil retryer`},
+		{store: store, r: r, attempts: 0, steps: []pipeline.Step{{Name: `a`, Run: run}}, msg: `Must not allow non positive attempts`},
+		{store: store, r: r, attempts: 1, steps: nil, msg: `Must not allow no steps`},
+		{store: store, r: r, attempts: 1, steps: []pipeline.Step{{Run: run}}, msg: `Must not allow an unnamed step`},
+		{store: store, r: r, attempts: 1, steps: []pipeline.Step{{Name: `a`}}, msg: `Must not allow a step without a function`},
+		{store: store, r: r, attempts: 1, steps: []pipeline.Step{{Name: `a`, Run: run}, {Name: `a`, Run: run}}, msg: `Must not allow duplicate steps`},
+	}
+	for _, test := range tests {
+		_, err := pipeline.New(test.store, test.r, test.attempts, test.steps...)
+		assert.Error(t, err, test.msg)
+	}
+}
+
+func TestPipelineResumes(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "pipeline")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fs, err := pipeline.NewFileStore(dir)
+	require.NoError(t, err)
+
+	for _, store := range []pipeline.Store{pipeline.NewMemoryStore(), fs} {
+		var (
+			called = make(map[string]int)
+			upload = errors.New(`upload unavailable`)
+			broken = true
+		)
+		step := func(name string) pipeline.Step {
+			return pipeline.Step{Name: name, Run: func(_ context.Context, in []byte) ([]byte, error) {
+				called[name]++
+				if name == `upload` && broken {
+					return nil, upload
+				}
+				return append(in, name[0]), nil
+			}}
+		}
+
+		p, err := pipeline.New(store, retry.Must(), 2, step(`extract`), step(`transform`), step(`upload`), step(`notify`))
+		require.NoError(t, err)
+
+		_, err = p.Run(context.Background(), `job/1`, []byte(`>`))
+		assert.True(t, errors.Is(err, upload))
+		assert.Contains(t, err.Error(), `pipeline step "upload" failed`)
+		assert.Equal(t, map[string]int{`extract`: 1, `transform`: 1, `upload`: 2}, called, `Each step must be retried`)
+
+		broken = false
+		out, err := p.Run(context.Background(), `job/1`, []byte(`>`))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(`>etun`), out, `Must resume with the checkpointed result`)
+		assert.Equal(t, map[string]int{`extract`: 1, `transform`: 1, `upload`: 3, `notify`: 1}, called, `Completed steps must not be run again`)
+
+		out, err = p.Run(context.Background(), `job/2`, []byte(`<`))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(`<etun`), out, `Jobs must be checkpointed separately`)
+
+		require.NoError(t, store.Clear(context.Background(), `job/1`))
+		_, done, err := store.Load(context.Background(), `job/1`, `extract`)
+		require.NoError(t, err)
+		assert.False(t, done, `Clear must remove all checkpoints`)
+	}
+}
+
+func TestPipelineStepOverrides(t *testing.T) {
+	t.Parallel()
+
+	called := 0
+	p, err := pipeline.New(pipeline.NewMemoryStore(), retry.Must(), 1, pipeline.Step{
+		Name:     `flaky`,
+		Attempts: 3,
+		Retryer:  retry.Must(retry.WithAbortOn(func(err error) bool { return false })),
+		Run: func(_ context.Context, in []byte) ([]byte, error) {
+			if called++; called < 3 {
+				return nil, errors.New(`transient`)
+			}
+			return in, nil
+		},
+	})
+	require.NoError(t, err)
+
+	out, err := p.Run(context.Background(), `job`, []byte(`in`))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`in`), out)
+	assert.Equal(t, 3, called, `Step attempts must override the pipeline`)
+}
+
+func TestFileStoreStaysWithinDir(t *testing.T) {
+	t.Parallel()
+
+	parent, err := ioutil.TempDir("", "pipeline")
+	require.NoError(t, err)
+	defer os.RemoveAll(parent)
+
+	sibling := filepath.Join(parent, "sibling")
+	require.NoError(t, ioutil.WriteFile(sibling, []byte(`keep`), 0o600))
+
+	fs, err := pipeline.NewFileStore(filepath.Join(parent, "store"))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for _, name := range []string{`..`, `.`, ``, `../sibling`} {
+		require.NoError(t, fs.Save(ctx, name, name, []byte(name)))
+		result, done, err := fs.Load(ctx, name, name)
+		require.NoError(t, err)
+		assert.True(t, done)
+		assert.Equal(t, []byte(name), result, `Must keep the checkpoint of %q separate`, name)
+		require.NoError(t, fs.Clear(ctx, name))
+	}
+
+	data, err := ioutil.ReadFile(sibling)
+	require.NoError(t, err, `Must not remove files outside of the store`)
+	assert.Equal(t, []byte(`keep`), data)
+	entries, err := ioutil.ReadDir(parent)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, `Must only write within the store`)
+}
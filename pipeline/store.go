@@ -0,0 +1,154 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Store persists the result of each completed step of a job,
+// so a job that is run again can resume from the first incomplete step.
+type Store interface {
+	// Load returns the saved result of the step for the job,
+	// and false if the step has not been completed.
+	Load(ctx context.Context, job, step string) ([]byte, bool, error)
+	// Save records the result of the completed step for the job.
+	Save(ctx context.Context, job, step string, result []byte) error
+	// Clear removes all the saved results for the job.
+	Clear(ctx context.Context, job string) error
+}
+
+// MemoryStore holds checkpoints in memory.
+type MemoryStore struct {
+	mu   sync.Mutex
+	jobs map[string]map[string][]byte
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty in memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: make(map[string]map[string][]byte)}
+}
+
+// Load implements Store
+func (m *MemoryStore) Load(_ context.Context, job, step string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result, exist := m.jobs[job][step]
+	return append([]byte(nil), result...), exist, nil
+}
+
+// Save implements Store
+func (m *MemoryStore) Save(_ context.Context, job, step string, result []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.jobs[job] == nil {
+		m.jobs[job] = make(map[string][]byte)
+	}
+	m.jobs[job][step] = append([]byte(nil), result...)
+	return nil
+}
+
+// Clear implements Store
+func (m *MemoryStore) Clear(_ context.Context, job string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.jobs, job)
+	return nil
+}
+
+// FileStore holds checkpoints as files within a directory,
+// with a directory for each job and a file for each completed step.
+type FileStore struct {
+	dir string
+}
+
+var _ Store = (*FileStore)(nil)
+
+// NewFileStore creates a store within dir, creating it if it does not exist.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// path returns the path for the job, and the step within it if given. Each name is hex encoded
+// with a prefix so that it can not be empty, hold a separator or be "." or "..",
+// and the joined path is checked to be within the directory of the store.
+func (fs *FileStore) path(job string, step ...string) (string, error) {
+	parts := []string{fs.dir}
+	for _, name := range append([]string{job}, step...) {
+		parts = append(parts, "_"+hex.EncodeToString([]byte(name)))
+	}
+	path := filepath.Join(parts...)
+	if rel, err := filepath.Rel(fs.dir, path); err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path for job %q is outside of the store", job)
+	}
+	return path, nil
+}
+
+// Load implements Store
+func (fs *FileStore) Load(_ context.Context, job, step string) ([]byte, bool, error) {
+	path, err := fs.path(job, step)
+	if err != nil {
+		return nil, false, err
+	}
+	result, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return result, true, nil
+}
+
+// Save implements Store
+func (fs *FileStore) Save(_ context.Context, job, step string, result []byte) error {
+	dir, err := fs.path(job)
+	if err != nil {
+		return err
+	}
+	path, err := fs.path(job, step)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, ".checkpoint")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(result); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// Renaming ensures a checkpoint is never partially written
+	return os.Rename(tmp.Name(), path)
+}
+
+// Clear implements Store
+func (fs *FileStore) Clear(_ context.Context, job string) error {
+	dir, err := fs.path(job)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
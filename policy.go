@@ -0,0 +1,171 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Policy applies resilience behaviour, such as retries or timeouts, to the execution of a function.
+// Policies are combined with Wrap so the order they are applied in is declared once.
+type Policy interface {
+	// Execute runs f with the behaviour of the policy applied.
+	Execute(ctx context.Context, f func(ctx context.Context) error) error
+}
+
+// PolicyFunc allows a function to be used as a Policy.
+type PolicyFunc func(ctx context.Context, f func(ctx context.Context) error) error
+
+var _ Policy = PolicyFunc(nil)
+
+// Execute implements Policy
+func (p PolicyFunc) Execute(ctx context.Context, f func(ctx context.Context) error) error {
+	return p(ctx, f)
+}
+
+// Wrap combines the policies into a single policy, where the first policy is the outer most
+// and the last policy is applied closest to the executed function. For example,
+//
+//	Wrap(fallback, timeout, retries, breaker)
+//
+// where each is created by FallbackPolicy, TimeoutPolicy, RetryPolicy and BreakerPolicy,
+// serves the fallback once the overall timeout or the retries have given up,
+// with each attempt made through the circuit breaker.
+// An error is returned if no policies are passed or any policy is nil.
+func Wrap(policies ...Policy) (Policy, error) {
+	if len(policies) == 0 {
+		return nil, errors.New(`no policies provided`)
+	}
+	for i, p := range policies {
+		if p == nil {
+			return nil, fmt.Errorf("policy %d is nil", i)
+		}
+	}
+	return PolicyFunc(func(ctx context.Context, f func(ctx context.Context) error) error {
+		if ctx == nil {
+			return errors.New(`invalid context provided`)
+		}
+		if f == nil {
+			return errors.New(`invalid function provided`)
+		}
+		for i := len(policies) - 1; i >= 0; i-- {
+			next, p := f, policies[i]
+			f = func(ctx context.Context) error {
+				return p.Execute(ctx, next)
+			}
+		}
+		return f(ctx)
+	}), nil
+}
+
+// interceptor adapts the policy so that it can be applied to each attempt made by a Retryer.
+func interceptor(p Policy) func(ctx context.Context, f func() error) error {
+	return func(ctx context.Context, f func() error) error {
+		return p.Execute(ctx, func(context.Context) error {
+			return f()
+		})
+	}
+}
+
+// RetryPolicy makes up to limit attempts using the Retryer.
+// An error is returned if the Retryer is nil.
+func RetryPolicy(r Retryer, limit int) (Policy, error) {
+	if r == nil {
+		return nil, errors.New(`retryer is nil`)
+	}
+	return PolicyFunc(func(ctx context.Context, f func(ctx context.Context) error) error {
+		return DoWithContextFunc(ctx, r, limit, f)
+	}), nil
+}
+
+// TimeoutPolicy cancels the context passed to the function once the timeout has passed.
+// An error is returned if the timeout is not positive.
+func TimeoutPolicy(timeout time.Duration) (Policy, error) {
+	if timeout <= 0 {
+		return nil, errors.New(`timeout must be a positive value`)
+	}
+	return PolicyFunc(func(ctx context.Context, f func(ctx context.Context) error) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return f(ctx)
+	}), nil
+}
+
+// errPanicked is reported to the circuit breaker when the function panics.
+var errPanicked = errors.New(`function panicked`)
+
+// BreakerPolicy executes the function through the circuit breaker, with any rejected executions
+// returned as aborted so an outer retry does not retry them. A panicking function is reported
+// to the breaker as failed before the panic continues. An error is returned if the breaker is nil.
+func BreakerPolicy(cb CircuitBreaker) (Policy, error) {
+	if cb == nil {
+		return nil, errors.New(`circuit breaker must not be nil`)
+	}
+	return PolicyFunc(func(ctx context.Context, f func(ctx context.Context) error) error {
+		done, err := cb.Allow()
+		if err != nil {
+			return AbortedRetries(err)
+		}
+		result := errPanicked
+		defer func() {
+			switch {
+			case abandoned(ctx, result):
+				done(context.Canceled)
+			case notReady(result):
+				done(nil)
+			default:
+				done(result)
+			}
+		}()
+		result = f(ctx)
+		return result
+	}), nil
+}
+
+// BulkheadPolicy holds the bulkhead while the function is executed, returning a
+// RejectedAttempt error if the bulkhead is full. An error is returned if the bulkhead is nil.
+func BulkheadPolicy(b *Bulkhead) (Policy, error) {
+	if b == nil {
+		return nil, errors.New(`bulkhead must not be nil`)
+	}
+	return PolicyFunc(func(ctx context.Context, f func(ctx context.Context) error) error {
+		if err := b.acquire(ctx); err != nil {
+			return err
+		}
+		defer b.release()
+		return f(ctx)
+	}), nil
+}
+
+// RateLimitPolicy waits for a token from the rate limiter before executing the function,
+// returning aborted if it gives up waiting. An error is returned if the rate limiter is nil.
+func RateLimitPolicy(l *RateLimiter) (Policy, error) {
+	if l == nil {
+		return nil, errors.New(`rate limiter must not be nil`)
+	}
+	return PolicyFunc(func(ctx context.Context, f func(ctx context.Context) error) error {
+		if err := l.Wait(ctx); err != nil {
+			return AbortedRetries(err)
+		}
+		return f(ctx)
+	}), nil
+}
+
+// FallbackPolicy calls the fallback with the error of the function if it fails,
+// behaving the same as WithFallback. An error is returned if the fallback is nil.
+func FallbackPolicy(fallback func(ctx context.Context, err error) error) (Policy, error) {
+	if fallback == nil {
+		return nil, errors.New(`fallback must not be nil`)
+	}
+	return PolicyFunc(func(ctx context.Context, f func(ctx context.Context) error) error {
+		err := f(ctx)
+		if err == nil {
+			return nil
+		}
+		if ferr := fallback(ctx, err); ferr != nil {
+			return ferr
+		}
+		return FellBack(err)
+	}), nil
+}
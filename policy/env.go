@@ -0,0 +1,134 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The suffixes of the environment variables read by FromEnv.
+const (
+	EnvAttempts     = "ATTEMPTS"
+	EnvBackoff      = "BACKOFF"
+	EnvJitter       = "JITTER"
+	EnvRetryable    = "RETRYABLE"
+	EnvStatusCodes  = "STATUS_CODES"
+	EnvStatusGroups = "STATUS_GROUPS"
+)
+
+// envFields maps the fields of the policy to the environment variable they are read from.
+var envFields = map[string]string{
+	"attempts":      EnvAttempts,
+	"strategy":      EnvBackoff,
+	"base_delay":    EnvBackoff,
+	"multiplier":    EnvBackoff,
+	"max_delay":     EnvBackoff,
+	"jitter":        EnvJitter,
+	"retryable":     EnvRetryable,
+	"status_codes":  EnvStatusCodes,
+	"status_groups": EnvStatusGroups,
+}
+
+// FromEnv resolves the policy by overriding the base policy with the environment variables
+// under the prefix, for example with the prefix PAYMENTS:
+//
+//	PAYMENTS_RETRY_ATTEMPTS=5
+//	PAYMENTS_RETRY_BACKOFF=exponential:200ms:2.0:30s
+//	PAYMENTS_RETRY_JITTER=100ms
+//	PAYMENTS_RETRY_RETRYABLE=timeout,network
+//	PAYMENTS_RETRY_STATUS_CODES=429,503
+//	PAYMENTS_RETRY_STATUS_GROUPS=5xx
+//
+// Variables that are unset or empty keep the value of the base policy.
+// The resolved policy is validated, and any error is reported as a FieldError
+// with the name of the variable as its path.
+// The resolved policy can be logged on start up, and used to create a Retryer or transport options.
+func FromEnv(prefix string, base Policy) (Policy, error) {
+	if prefix != "" {
+		prefix += "_"
+	}
+	prefix += "RETRY_"
+
+	p := base
+	for _, name := range []string{EnvAttempts, EnvBackoff, EnvJitter, EnvRetryable, EnvStatusCodes, EnvStatusGroups} {
+		value, ok := os.LookupEnv(prefix + name)
+		if value = strings.TrimSpace(value); !ok || value == "" {
+			continue
+		}
+		var err error
+		switch name {
+		case EnvAttempts:
+			p.Attempts, err = strconv.Atoi(value)
+			if err != nil {
+				err = fmt.Errorf("invalid attempts %q", value)
+			}
+		case EnvBackoff:
+			err = parseStrategy(value, &p)
+		case EnvJitter:
+			var d time.Duration
+			d, err = time.ParseDuration(value)
+			if err != nil {
+				err = fmt.Errorf("invalid duration %q", value)
+			}
+			p.Jitter = Duration(d)
+		case EnvRetryable:
+			p.Retryable, err = splitList(value)
+		case EnvStatusCodes:
+			var codes []string
+			if codes, err = splitList(value); err == nil {
+				p.StatusCodes, err = parseCodes(codes)
+			}
+		case EnvStatusGroups:
+			p.StatusGroups, err = splitList(value)
+		}
+		if err != nil {
+			return Policy{}, &FieldError{Path: prefix + name, Err: err}
+		}
+	}
+
+	if err := p.Validate(); err != nil {
+		return Policy{}, relabel(err, func(name string) string { return prefix + name })
+	}
+	return p, nil
+}
+
+// relabel reports the validation error against the source of the field,
+// where source is passed the suffix of the environment variable for the field.
+func relabel(err error, source func(name string) string) error {
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		return err
+	}
+	field := fe.Path
+	if i := strings.IndexByte(field, '['); i >= 0 {
+		field = field[:i]
+	}
+	return &FieldError{Path: source(envFields[field]), Err: fmt.Errorf("%s %v", fe.Path, fe.Err)}
+}
+
+// parseCodes converts the status codes to integers.
+func parseCodes(elems []string) ([]int, error) {
+	codes := make([]int, 0, len(elems))
+	for _, elem := range elems {
+		code, err := strconv.Atoi(elem)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status code %q", elem)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// splitList splits the comma separated list, rejecting any empty element.
+func splitList(value string) ([]string, error) {
+	elems := strings.Split(value, ",")
+	for i, elem := range elems {
+		if elems[i] = strings.TrimSpace(elem); elems[i] == "" {
+			return nil, fmt.Errorf("empty element in list %q", value)
+		}
+	}
+	return elems, nil
+}
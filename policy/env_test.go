@@ -0,0 +1,109 @@
+package policy_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry/http/transport"
+	"github.com/MovieStoreGuy/retry/policy"
+)
+
+func setenv(t *testing.T, env map[string]string) func() {
+	for k, v := range env {
+		require.NoError(t, os.Setenv(k, v))
+	}
+	return func() {
+		for k := range env {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Parallel()
+
+	defer setenv(t, map[string]string{
+		"PAYMENTS_RETRY_ATTEMPTS":      "5",
+		"PAYMENTS_RETRY_BACKOFF":       "exponential:200ms:2.0:30s",
+		"PAYMENTS_RETRY_JITTER":        "100ms",
+		"PAYMENTS_RETRY_RETRYABLE":     "timeout, network",
+		"PAYMENTS_RETRY_STATUS_CODES":  "429,503",
+		"PAYMENTS_RETRY_STATUS_GROUPS": "",
+	})()
+
+	base := policy.Policy{Attempts: 3, Strategy: policy.StrategyNone, StatusGroups: []string{"5xx"}}
+	p, err := policy.FromEnv("PAYMENTS", base)
+	require.NoError(t, err)
+	assert.Equal(t, policy.Policy{
+		Attempts:     5,
+		Strategy:     policy.StrategyExponential,
+		BaseDelay:    policy.Duration(200 * time.Millisecond),
+		Multiplier:   2.0,
+		MaxDelay:     policy.Duration(30 * time.Second),
+		Jitter:       policy.Duration(100 * time.Millisecond),
+		Retryable:    []string{policy.ClassTimeout, policy.ClassNetwork},
+		StatusCodes:  []int{429, 503},
+		StatusGroups: []string{"5xx"},
+	}, p, `Must override the base policy with any set variables`)
+	assert.Equal(t,
+		`attempts=5 backoff=exponential:200ms:2:30s jitter=100ms retryable=timeout,network status_codes=429,503 status_groups=5xx`,
+		p.String(),
+	)
+
+	_, err = p.Retryer()
+	assert.NoError(t, err)
+	opts, err := p.TransportOptions()
+	require.NoError(t, err)
+	_, err = transport.Default(p.Attempts, opts...)
+	assert.NoError(t, err)
+
+	p, err = policy.FromEnv("UNSET", base)
+	require.NoError(t, err)
+	assert.Equal(t, base, p, `Must use the base policy when no variables are set`)
+	assert.Equal(t, `attempts=3 backoff=none status_groups=5xx`, p.String())
+
+	_, err = policy.FromEnv("UNSET", policy.Policy{})
+	assert.Error(t, err, `Must validate the resolved policy`)
+}
+
+func TestFromEnvMalformed(t *testing.T) {
+	t.Parallel()
+
+	base := policy.Policy{Attempts: 3, Strategy: policy.StrategyNone}
+	tests := []struct {
+		name, value string
+	}{
+		{name: "MALFORMED_RETRY_ATTEMPTS", value: "three"},
+		{name: "MALFORMED_RETRY_ATTEMPTS", value: "0"},
+		{name: "MALFORMED_RETRY_BACKOFF", value: "linear:1s"},
+		{name: "MALFORMED_RETRY_BACKOFF", value: "none:1s"},
+		{name: "MALFORMED_RETRY_BACKOFF", value: "fixed"},
+		{name: "MALFORMED_RETRY_BACKOFF", value: "fixed:soon"},
+		{name: "MALFORMED_RETRY_BACKOFF", value: "exponential:200ms:two"},
+		{name: "MALFORMED_RETRY_BACKOFF", value: "exponential:200ms:2.0:later"},
+		{name: "MALFORMED_RETRY_BACKOFF", value: "exponential:200ms:2.0:30s:1m"},
+		{name: "MALFORMED_RETRY_BACKOFF", value: "exponential:1s:0.5"},
+		{name: "MALFORMED_RETRY_BACKOFF", value: "exponential:1s:2:500ms"},
+		{name: "MALFORMED_RETRY_JITTER", value: "a little"},
+		{name: "MALFORMED_RETRY_RETRYABLE", value: "timeout,,network"},
+		{name: "MALFORMED_RETRY_RETRYABLE", value: "cosmic rays"},
+		{name: "MALFORMED_RETRY_STATUS_CODES", value: "500,five hundred"},
+		{name: "MALFORMED_RETRY_STATUS_CODES", value: "600"},
+		{name: "MALFORMED_RETRY_STATUS_GROUPS", value: "5xx,50x"},
+	}
+	for _, test := range tests {
+		unset := setenv(t, map[string]string{test.name: test.value})
+		_, err := policy.FromEnv("MALFORMED", base)
+		unset()
+
+		var fe *policy.FieldError
+		if assert.True(t, errors.As(err, &fe), `Must report a field error for %s=%s`, test.name, test.value) {
+			assert.Equal(t, test.name, fe.Path, `Must report the malformed variable`)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package policy
+
+import (
+	"flag"
+	"strconv"
+	"strings"
+
+	"github.com/MovieStoreGuy/retry"
+	"github.com/MovieStoreGuy/retry/http/transport"
+)
+
+// Flags holds the policy set by the flags registered with RegisterFlags.
+type Flags struct {
+	prefix string
+	policy Policy
+}
+
+type (
+	strategyValue struct{ p *Policy }
+	listValue     struct{ elems *[]string }
+	codesValue    struct{ codes *[]int }
+)
+
+var (
+	_ flag.Value = (*Duration)(nil)
+	_ flag.Value = strategyValue{}
+	_ flag.Value = listValue{}
+	_ flag.Value = codesValue{}
+)
+
+// RegisterFlags registers the retry flags on the flag set under the prefix, using
+// the defaults policy for their default values. For example with the prefix payments:
+//
+//	-payments-retry-attempts=5
+//	-payments-retry-backoff=exponential:200ms:2.0:30s
+//	-payments-retry-jitter=100ms
+//	-payments-retry-retryable=timeout,network
+//	-payments-retry-status-codes=429,503
+//	-payments-retry-status-groups=5xx
+//
+// which accept the same values as FromEnv. The flags are named retry-attempts and so on
+// when the prefix is empty. Once the flag set is parsed, the policy is read from the returned Flags.
+func RegisterFlags(fs *flag.FlagSet, prefix string, defaults Policy) *Flags {
+	f := &Flags{prefix: prefix, policy: defaults}
+	p := &f.policy
+	fs.IntVar(&p.Attempts, f.name(EnvAttempts), defaults.Attempts, "the limit of attempts made")
+	fs.Var(strategyValue{p: p}, f.name(EnvBackoff), "the backoff strategy between attempts: none, fixed:<delay>[:<max delay>] or exponential:<base delay>[:<multiplier>[:<max delay>]]")
+	fs.Var(&p.Jitter, f.name(EnvJitter), "the random delay added to each backoff")
+	fs.Var(listValue{elems: &p.Retryable}, f.name(EnvRetryable), "comma separated error classes that are retried: timeout, temporary or network")
+	fs.Var(codesValue{codes: &p.StatusCodes}, f.name(EnvStatusCodes), "comma separated response status codes that are retried")
+	fs.Var(listValue{elems: &p.StatusGroups}, f.name(EnvStatusGroups), "comma separated response status groups that are retried, such as 5xx")
+	return f
+}
+
+func (f *Flags) name(suffix string) string {
+	name := "retry-" + strings.ToLower(strings.ReplaceAll(suffix, "_", "-"))
+	if f.prefix != "" {
+		name = f.prefix + "-" + name
+	}
+	return name
+}
+
+// Policy validates the policy set by the flags, any error is reported
+// as a FieldError with the name of the flag as its path.
+func (f *Flags) Policy() (Policy, error) {
+	if err := f.policy.Validate(); err != nil {
+		return Policy{}, relabel(err, func(name string) string { return "-" + f.name(name) })
+	}
+	return f.policy, nil
+}
+
+// Options returns the retry options of the policy set by the flags.
+func (f *Flags) Options() ([]retry.Option, error) {
+	p, err := f.Policy()
+	if err != nil {
+		return nil, err
+	}
+	return p.Options()
+}
+
+// TransportOptions returns the transport options of the policy set by the flags.
+func (f *Flags) TransportOptions() ([]transport.Option, error) {
+	p, err := f.Policy()
+	if err != nil {
+		return nil, err
+	}
+	return p.TransportOptions()
+}
+
+func (v strategyValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return formatStrategy(*v.p)
+}
+
+func (v strategyValue) Set(spec string) error {
+	return parseStrategy(spec, v.p)
+}
+
+func (v listValue) String() string {
+	if v.elems == nil {
+		return ""
+	}
+	return strings.Join(*v.elems, ",")
+}
+
+func (v listValue) Set(value string) error {
+	elems, err := splitList(value)
+	if err != nil {
+		return err
+	}
+	*v.elems = elems
+	return nil
+}
+
+func (v codesValue) String() string {
+	if v.codes == nil {
+		return ""
+	}
+	codes := make([]string, 0, len(*v.codes))
+	for _, code := range *v.codes {
+		codes = append(codes, strconv.Itoa(code))
+	}
+	return strings.Join(codes, ",")
+}
+
+func (v codesValue) Set(value string) error {
+	elems, err := splitList(value)
+	if err != nil {
+		return err
+	}
+	codes, err := parseCodes(elems)
+	if err != nil {
+		return err
+	}
+	*v.codes = codes
+	return nil
+}
@@ -0,0 +1,106 @@
+package policy_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry/http/transport"
+	"github.com/MovieStoreGuy/retry/policy"
+)
+
+func TestRegisterFlags(t *testing.T) {
+	t.Parallel()
+
+	defaults := policy.Policy{
+		Attempts:  3,
+		Strategy:  policy.StrategyFixed,
+		BaseDelay: policy.Duration(time.Second),
+	}
+
+	fs := flag.NewFlagSet("tool", flag.ContinueOnError)
+	f := policy.RegisterFlags(fs, "payments", defaults)
+	require.NoError(t, fs.Parse([]string{
+		"-payments-retry-attempts=5",
+		"-payments-retry-backoff=exponential:200ms:2.0:30s",
+		"-payments-retry-jitter=100ms",
+		"-payments-retry-retryable=timeout,network",
+		"-payments-retry-status-codes=429,503",
+		"-payments-retry-status-groups=5xx",
+	}))
+
+	p, err := f.Policy()
+	require.NoError(t, err)
+	assert.Equal(t, policy.Policy{
+		Attempts:     5,
+		Strategy:     policy.StrategyExponential,
+		BaseDelay:    policy.Duration(200 * time.Millisecond),
+		Multiplier:   2.0,
+		MaxDelay:     policy.Duration(30 * time.Second),
+		Jitter:       policy.Duration(100 * time.Millisecond),
+		Retryable:    []string{policy.ClassTimeout, policy.ClassNetwork},
+		StatusCodes:  []int{429, 503},
+		StatusGroups: []string{"5xx"},
+	}, p)
+
+	_, err = f.Options()
+	assert.NoError(t, err)
+	opts, err := f.TransportOptions()
+	require.NoError(t, err)
+	_, err = transport.Default(p.Attempts, opts...)
+	assert.NoError(t, err)
+
+	fs = flag.NewFlagSet("tool", flag.ContinueOnError)
+	f = policy.RegisterFlags(fs, "", defaults)
+	require.NoError(t, fs.Parse(nil))
+	p, err = f.Policy()
+	require.NoError(t, err)
+	assert.Equal(t, defaults, p, `Must use the defaults when no flags are set`)
+
+	var usage bytes.Buffer
+	fs.SetOutput(&usage)
+	fs.PrintDefaults()
+	assert.Contains(t, usage.String(), `-retry-backoff`)
+	assert.Contains(t, usage.String(), `(default fixed:1s)`, `Must show the default strategy spec`)
+}
+
+func TestRegisterFlagsMalformed(t *testing.T) {
+	t.Parallel()
+
+	defaults := policy.Policy{Attempts: 3, Strategy: policy.StrategyNone}
+	for _, arg := range []string{
+		"-retry-attempts=three",
+		"-retry-backoff=linear:1s",
+		"-retry-backoff=exponential:200ms:two",
+		"-retry-jitter=a little",
+		"-retry-retryable=timeout,,network",
+		"-retry-status-codes=five hundred",
+	} {
+		fs := flag.NewFlagSet("tool", flag.ContinueOnError)
+		fs.SetOutput(&bytes.Buffer{})
+		policy.RegisterFlags(fs, "", defaults)
+		assert.Error(t, fs.Parse([]string{arg}), `Must reject the malformed flag %s`, arg)
+	}
+
+	for arg, name := range map[string]string{
+		"-retry-attempts=0":                     "-retry-attempts",
+		"-retry-backoff=exponential:1s:2:500ms": "-retry-backoff",
+		"-retry-retryable=cosmic rays":          "-retry-retryable",
+		"-retry-status-groups=50x":              "-retry-status-groups",
+	} {
+		fs := flag.NewFlagSet("tool", flag.ContinueOnError)
+		f := policy.RegisterFlags(fs, "", defaults)
+		require.NoError(t, fs.Parse([]string{arg}))
+
+		_, err := f.Policy()
+		var fe *policy.FieldError
+		if assert.True(t, errors.As(err, &fe), `Must report a field error for %s`, arg) {
+			assert.Equal(t, name, fe.Path, `Must report the invalid flag`)
+		}
+	}
+}
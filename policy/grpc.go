@@ -0,0 +1,250 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MovieStoreGuy/retry"
+	"github.com/MovieStoreGuy/retry/http/transport"
+)
+
+// Code is a gRPC status code, using the same values as google.golang.org/grpc/codes
+// so that a code can be converted with Code(status.Code(err)).
+type Code uint32
+
+// The gRPC status codes.
+const (
+	CodeOK Code = iota
+	CodeCanceled
+	CodeUnknown
+	CodeInvalidArgument
+	CodeDeadlineExceeded
+	CodeNotFound
+	CodeAlreadyExists
+	CodePermissionDenied
+	CodeResourceExhausted
+	CodeFailedPrecondition
+	CodeAborted
+	CodeOutOfRange
+	CodeUnimplemented
+	CodeInternal
+	CodeUnavailable
+	CodeDataLoss
+	CodeUnauthenticated
+)
+
+// MaxGRPCAttempts is the most attempts a gRPC retry policy allows,
+// any larger maxAttempts is treated as this value.
+const MaxGRPCAttempts = 5
+
+var codeNames = [...]string{
+	CodeOK:                 "OK",
+	CodeCanceled:           "CANCELLED",
+	CodeUnknown:            "UNKNOWN",
+	CodeInvalidArgument:    "INVALID_ARGUMENT",
+	CodeDeadlineExceeded:   "DEADLINE_EXCEEDED",
+	CodeNotFound:           "NOT_FOUND",
+	CodeAlreadyExists:      "ALREADY_EXISTS",
+	CodePermissionDenied:   "PERMISSION_DENIED",
+	CodeResourceExhausted:  "RESOURCE_EXHAUSTED",
+	CodeFailedPrecondition: "FAILED_PRECONDITION",
+	CodeAborted:            "ABORTED",
+	CodeOutOfRange:         "OUT_OF_RANGE",
+	CodeUnimplemented:      "UNIMPLEMENTED",
+	CodeInternal:           "INTERNAL",
+	CodeUnavailable:        "UNAVAILABLE",
+	CodeDataLoss:           "DATA_LOSS",
+	CodeUnauthenticated:    "UNAUTHENTICATED",
+}
+
+func (c Code) String() string {
+	if int(c) < len(codeNames) {
+		return codeNames[c]
+	}
+	return fmt.Sprintf("CODE(%d)", uint32(c))
+}
+
+// GRPCRetryPolicy is the retryPolicy of a method config within a gRPC service config, for example
+//
+//	{
+//		"maxAttempts": 4,
+//		"initialBackoff": "0.1s",
+//		"maxBackoff": "1s",
+//		"backoffMultiplier": 2,
+//		"retryableStatusCodes": ["UNAVAILABLE"]
+//	}
+//
+// The delay after the nth failed attempt is random(0, min(initialBackoff * backoffMultiplier^(n-1), maxBackoff))
+// as described by the gRPC retry design.
+type GRPCRetryPolicy struct {
+	MaxAttempts          int
+	InitialBackoff       time.Duration
+	MaxBackoff           time.Duration
+	BackoffMultiplier    float64
+	RetryableStatusCodes []Code
+}
+
+// ParseGRPC decodes the JSON encoded retryPolicy and validates it, reporting any invalid field as a FieldError.
+// Durations are encoded in seconds with an "s" suffix, such as "0.1s", and status codes are either
+// their name, such as "UNAVAILABLE", or their value. A maxAttempts over MaxGRPCAttempts is reduced to it.
+func ParseGRPC(data []byte) (GRPCRetryPolicy, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return GRPCRetryPolicy{}, err
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var p GRPCRetryPolicy
+	for _, name := range names {
+		raw := fields[name]
+		var err error
+		switch name {
+		case "maxAttempts":
+			err = json.Unmarshal(raw, &p.MaxAttempts)
+		case "initialBackoff":
+			p.InitialBackoff, err = parseProtoDuration(raw)
+		case "maxBackoff":
+			p.MaxBackoff, err = parseProtoDuration(raw)
+		case "backoffMultiplier":
+			err = json.Unmarshal(raw, &p.BackoffMultiplier)
+		case "retryableStatusCodes":
+			err = decodeList(name, raw, func(elem json.RawMessage) error {
+				code, err := parseCode(elem)
+				p.RetryableStatusCodes = append(p.RetryableStatusCodes, code)
+				return err
+			})
+		default:
+			err = errors.New(`unknown field`)
+		}
+		if err != nil {
+			var fe *FieldError
+			if errors.As(err, &fe) {
+				return GRPCRetryPolicy{}, err
+			}
+			return GRPCRetryPolicy{}, &FieldError{Path: name, Err: err}
+		}
+	}
+	if p.MaxAttempts > MaxGRPCAttempts {
+		p.MaxAttempts = MaxGRPCAttempts
+	}
+	if err := p.Validate(); err != nil {
+		return GRPCRetryPolicy{}, err
+	}
+	return p, nil
+}
+
+// parseProtoDuration parses the JSON encoding of a protobuf Duration, such as "1.5s".
+func parseProtoDuration(raw json.RawMessage) (time.Duration, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, fmt.Errorf("duration must be a string such as \"0.1s\", got %s", raw)
+	}
+	seconds := strings.TrimSuffix(s, "s")
+	valid := seconds != s && seconds != "" && strings.Count(seconds, ".") <= 1
+	for _, c := range seconds {
+		valid = valid && (c == '.' || (c >= '0' && c <= '9'))
+	}
+	if !valid {
+		return 0, fmt.Errorf("invalid duration %q, expected seconds such as \"0.1s\"", s)
+	}
+	return time.ParseDuration(s)
+}
+
+func parseCode(raw json.RawMessage) (Code, error) {
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		for c, n := range codeNames {
+			if n == name {
+				return Code(c), nil
+			}
+		}
+		return 0, fmt.Errorf("unknown status code %q", name)
+	}
+	var value int
+	if err := json.Unmarshal(raw, &value); err != nil || value < 0 || value >= len(codeNames) {
+		return 0, fmt.Errorf("invalid status code %s", raw)
+	}
+	return Code(value), nil
+}
+
+// Validate checks the policy against the requirements of the gRPC retry design,
+// reporting the first invalid field as a FieldError.
+func (p GRPCRetryPolicy) Validate() error {
+	if p.MaxAttempts < 2 {
+		return &FieldError{Path: "maxAttempts", Err: errors.New(`must be greater than 1`)}
+	}
+	if p.InitialBackoff <= 0 {
+		return &FieldError{Path: "initialBackoff", Err: errors.New(`must be positive`)}
+	}
+	if p.MaxBackoff <= 0 {
+		return &FieldError{Path: "maxBackoff", Err: errors.New(`must be positive`)}
+	}
+	if p.BackoffMultiplier <= 0 {
+		return &FieldError{Path: "backoffMultiplier", Err: errors.New(`must be positive`)}
+	}
+	if len(p.RetryableStatusCodes) == 0 {
+		return &FieldError{Path: "retryableStatusCodes", Err: errors.New(`must not be empty`)}
+	}
+	for i, code := range p.RetryableStatusCodes {
+		if int(code) >= len(codeNames) {
+			return &FieldError{Path: fmt.Sprintf("retryableStatusCodes[%d]", i), Err: fmt.Errorf("invalid status code %d", uint32(code))}
+		}
+	}
+	return nil
+}
+
+// Options validates the policy and returns the equivalent retry options, where code
+// returns the status code of an attempt error, such as:
+//
+//	func(err error) policy.Code { return policy.Code(status.Code(err)) }
+//
+// Any error that does not have a retryable status code aborts the retries.
+func (p GRPCRetryPolicy) Options(code func(err error) Code) ([]retry.Option, error) {
+	if code == nil {
+		return nil, errors.New(`code function must not be nil`)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	retryable := make(map[Code]struct{}, len(p.RetryableStatusCodes))
+	for _, c := range p.RetryableStatusCodes {
+		retryable[c] = struct{}{}
+	}
+	return []retry.Option{
+		retry.WithFullJitterBackoff(p.InitialBackoff, p.BackoffMultiplier, p.MaxBackoff),
+		retry.WithAbortOn(func(err error) bool {
+			_, ok := retryable[code(err)]
+			return !ok
+		}),
+	}, nil
+}
+
+// Retryer validates the policy and creates a Retryer from it,
+// which is expected to be called with MaxAttempts as the limit.
+func (p GRPCRetryPolicy) Retryer(code func(err error) Code) (retry.Retryer, error) {
+	opts, err := p.Options(code)
+	if err != nil {
+		return nil, err
+	}
+	return retry.New(opts...)
+}
+
+// TransportOptions validates the policy and returns transport options with the same backoff,
+// so an HTTP client can share the policy with a gRPC client.
+// The retryable status codes are not applied since they are specific to gRPC.
+func (p GRPCRetryPolicy) TransportOptions() ([]transport.Option, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return []transport.Option{
+		transport.WithRetryOptions(retry.WithFullJitterBackoff(p.InitialBackoff, p.BackoffMultiplier, p.MaxBackoff)),
+	}, nil
+}
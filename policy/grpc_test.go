@@ -0,0 +1,171 @@
+package policy_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+	"github.com/MovieStoreGuy/retry/http/transport"
+	"github.com/MovieStoreGuy/retry/policy"
+)
+
+type statusError struct{ code policy.Code }
+
+func (se statusError) Error() string { return se.code.String() }
+
+func codeOf(err error) policy.Code {
+	var se statusError
+	if errors.As(err, &se) {
+		return se.code
+	}
+	return policy.CodeUnknown
+}
+
+func TestParseGRPC(t *testing.T) {
+	t.Parallel()
+
+	p, err := policy.ParseGRPC([]byte(`{
+		"maxAttempts": 4,
+		"initialBackoff": "0.1s",
+		"maxBackoff": "1.5s",
+		"backoffMultiplier": 2,
+		"retryableStatusCodes": ["UNAVAILABLE", 8]
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, policy.GRPCRetryPolicy{
+		MaxAttempts:          4,
+		InitialBackoff:       100 * time.Millisecond,
+		MaxBackoff:           1500 * time.Millisecond,
+		BackoffMultiplier:    2,
+		RetryableStatusCodes: []policy.Code{policy.CodeUnavailable, policy.CodeResourceExhausted},
+	}, p)
+
+	p, err = policy.ParseGRPC([]byte(`{
+		"maxAttempts": 10,
+		"initialBackoff": "1s",
+		"maxBackoff": "10s",
+		"backoffMultiplier": 1.5,
+		"retryableStatusCodes": ["UNAVAILABLE"]
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, policy.MaxGRPCAttempts, p.MaxAttempts, `Must treat any larger max attempts as the limit`)
+
+	assert.Equal(t, "UNAVAILABLE", policy.CodeUnavailable.String())
+	assert.Equal(t, "CODE(42)", policy.Code(42).String())
+}
+
+func TestParseGRPCFieldPath(t *testing.T) {
+	t.Parallel()
+
+	const valid = `"initialBackoff": "0.1s", "maxBackoff": "1s", "backoffMultiplier": 2, "retryableStatusCodes": ["UNAVAILABLE"]`
+	tests := []struct {
+		data string
+		path string
+	}{
+		{data: `{"maxAttempts": 1, ` + valid + `}`, path: `maxAttempts`},
+		{data: `{"maxAttempts": "3", ` + valid + `}`, path: `maxAttempts`},
+		{data: `{"maxAttempts": 3, "retries": 2, ` + valid + `}`, path: `retries`},
+		{data: `{"maxAttempts": 3, "initialBackoff": "100ms", "maxBackoff": "1s", "backoffMultiplier": 2, "retryableStatusCodes": ["UNAVAILABLE"]}`, path: `initialBackoff`},
+		{data: `{"maxAttempts": 3, "initialBackoff": 0.1, "maxBackoff": "1s", "backoffMultiplier": 2, "retryableStatusCodes": ["UNAVAILABLE"]}`, path: `initialBackoff`},
+		{data: `{"maxAttempts": 3, "initialBackoff": "0s", "maxBackoff": "1s", "backoffMultiplier": 2, "retryableStatusCodes": ["UNAVAILABLE"]}`, path: `initialBackoff`},
+		{data: `{"maxAttempts": 3, "initialBackoff": "0.1s", "maxBackoff": "-1s", "backoffMultiplier": 2, "retryableStatusCodes": ["UNAVAILABLE"]}`, path: `maxBackoff`},
+		{data: `{"maxAttempts": 3, "initialBackoff": "0.1s", "backoffMultiplier": 2, "retryableStatusCodes": ["UNAVAILABLE"]}`, path: `maxBackoff`},
+		{data: `{"maxAttempts": 3, "initialBackoff": "0.1s", "maxBackoff": "1s", "backoffMultiplier": 0, "retryableStatusCodes": ["UNAVAILABLE"]}`, path: `backoffMultiplier`},
+		{data: `{"maxAttempts": 3, "initialBackoff": "0.1s", "maxBackoff": "1s", "backoffMultiplier": 2, "retryableStatusCodes": []}`, path: `retryableStatusCodes`},
+		{data: `{"maxAttempts": 3, "initialBackoff": "0.1s", "maxBackoff": "1s", "backoffMultiplier": 2, "retryableStatusCodes": ["UNAVAILABLE", "BUSY"]}`, path: `retryableStatusCodes[1]`},
+		{data: `{"maxAttempts": 3, "initialBackoff": "0.1s", "maxBackoff": "1s", "backoffMultiplier": 2, "retryableStatusCodes": [14, 17]}`, path: `retryableStatusCodes[1]`},
+	}
+	for _, test := range tests {
+		_, err := policy.ParseGRPC([]byte(test.data))
+		var fe *policy.FieldError
+		if assert.True(t, errors.As(err, &fe), `Must report a field error for %s`, test.data) {
+			assert.Equal(t, test.path, fe.Path, `Must report the path of the invalid field for %s`, test.data)
+		}
+	}
+}
+
+// TestGRPCBackoffConformance checks the delays against the gRPC retry design, where
+// the delay after the nth failed attempt is random(0, min(initialBackoff*backoffMultiplier^(n-1), maxBackoff)).
+func TestGRPCBackoffConformance(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range []policy.GRPCRetryPolicy{
+		{MaxAttempts: 5, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, BackoffMultiplier: 2},
+		{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: 2 * time.Second, BackoffMultiplier: 1.5},
+		{MaxAttempts: 5, InitialBackoff: 300 * time.Millisecond, MaxBackoff: 300 * time.Millisecond, BackoffMultiplier: 3},
+		{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: 10 * time.Second, BackoffMultiplier: 0.5},
+	} {
+		p.RetryableStatusCodes = []policy.Code{policy.CodeUnavailable}
+		r, err := p.Retryer(codeOf)
+		require.NoError(t, err)
+
+		for n := 1; n < p.MaxAttempts; n++ {
+			ceiling := math.Min(
+				float64(p.InitialBackoff)*math.Pow(p.BackoffMultiplier, float64(n-1)),
+				float64(p.MaxBackoff),
+			)
+			var (
+				highest time.Duration
+				sum     float64
+			)
+			const samples = 2000
+			for i := 0; i < samples; i++ {
+				d := retry.Backoff(r, n, p.MaxAttempts)
+				require.GreaterOrEqual(t, int64(d), int64(0))
+				require.Less(t, float64(d), ceiling, `Delay after attempt %d must be less than the ceiling for %+v`, n, p)
+				if d > highest {
+					highest = d
+				}
+				sum += float64(d)
+			}
+			assert.Greater(t, float64(highest), 0.9*ceiling, `Delay after attempt %d must be spread up to the ceiling for %+v`, n, p)
+			assert.InDelta(t, ceiling/2, sum/samples, 0.1*ceiling, `Delay after attempt %d must be uniform for %+v`, n, p)
+		}
+	}
+}
+
+func TestGRPCRetryer(t *testing.T) {
+	t.Parallel()
+
+	p := policy.GRPCRetryPolicy{
+		MaxAttempts:          3,
+		InitialBackoff:       time.Millisecond,
+		MaxBackoff:           time.Millisecond,
+		BackoffMultiplier:    2,
+		RetryableStatusCodes: []policy.Code{policy.CodeUnavailable},
+	}
+
+	_, err := p.Retryer(nil)
+	assert.Error(t, err, `Must not allow a nil code function`)
+	_, err = policy.GRPCRetryPolicy{}.Retryer(codeOf)
+	assert.Error(t, err, `Must validate the policy`)
+
+	r, err := p.Retryer(codeOf)
+	require.NoError(t, err)
+
+	attempts := 0
+	err = r.Do(p.MaxAttempts, func() error {
+		attempts++
+		return statusError{code: policy.CodeUnavailable}
+	})
+	assert.True(t, retry.HasExceeded(err), `Must retry retryable status codes`)
+	assert.Equal(t, p.MaxAttempts, attempts)
+
+	attempts = 0
+	err = r.Do(p.MaxAttempts, func() error {
+		attempts++
+		return statusError{code: policy.CodeInvalidArgument}
+	})
+	assert.True(t, retry.HasAborted(err), `Must abort status codes that are not retryable`)
+	assert.Equal(t, 1, attempts)
+
+	opts, err := p.TransportOptions()
+	require.NoError(t, err)
+	_, err = transport.Default(p.MaxAttempts, opts...)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,382 @@
+// Package policy describes a retry policy as data so it can be kept in service configuration,
+// then turned into a Retryer or into the options of http/transport.
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MovieStoreGuy/retry"
+	"github.com/MovieStoreGuy/retry/http/transport"
+)
+
+// The strategies that determine the delay between attempts.
+const (
+	StrategyNone        = "none"
+	StrategyFixed       = "fixed"
+	StrategyExponential = "exponential"
+)
+
+// The error classes that can be marked as retryable.
+const (
+	ClassTimeout   = "timeout"
+	ClassTemporary = "temporary"
+	ClassNetwork   = "network"
+)
+
+// DefaultMultiplier is used by the exponential strategy when no multiplier is set.
+const DefaultMultiplier = 2.0
+
+var classes = map[string]func(err error) bool{
+	ClassTimeout: func(err error) bool {
+		var t interface{ Timeout() bool }
+		return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &t) && t.Timeout())
+	},
+	ClassTemporary: func(err error) bool {
+		var t interface{ Temporary() bool }
+		return errors.As(err, &t) && t.Temporary()
+	},
+	ClassNetwork: func(err error) bool {
+		var n net.Error
+		return errors.As(err, &n)
+	},
+}
+
+// Policy is the serialisable form of the retry options, for example
+//
+//	{
+//		"attempts": 5,
+//		"strategy": "exponential",
+//		"base_delay": "250ms",
+//		"max_delay": "10s",
+//		"jitter": "100ms",
+//		"retryable": ["timeout", "network"],
+//		"status_groups": ["5xx"]
+//	}
+//
+// Decoding a Policy is strict, any unknown field or value of the wrong type is reported as a FieldError.
+type Policy struct {
+	// Attempts is the limit of attempts to pass to the Retryer.
+	Attempts int `json:"attempts"`
+	// Strategy is one of none, fixed or exponential.
+	Strategy string `json:"strategy"`
+	// BaseDelay is the fixed delay, or the delay before the first retry of the exponential
+	// strategy which is multiplied by Multiplier for each following retry.
+	BaseDelay Duration `json:"base_delay,omitempty"`
+	// MaxDelay caps the delay between attempts when set.
+	MaxDelay Duration `json:"max_delay,omitempty"`
+	// Multiplier is only used by the exponential strategy, defaulting to DefaultMultiplier.
+	Multiplier float64 `json:"multiplier,omitempty"`
+	// Jitter adds a random delay between [0, Jitter) to each delay when set.
+	Jitter Duration `json:"jitter,omitempty"`
+	// Retryable lists the error classes that are retried, any other error aborts the retries.
+	// All errors are retried when it is empty.
+	Retryable []string `json:"retryable,omitempty"`
+	// StatusCodes are the response status codes that are retried by the transport.
+	StatusCodes []int `json:"status_codes,omitempty"`
+	// StatusGroups are the response status groups, such as "5xx", that are retried by the transport.
+	StatusGroups []string `json:"status_groups,omitempty"`
+}
+
+// FieldError reports the path of the field within the policy that is invalid.
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (fe *FieldError) Error() string {
+	return fmt.Sprintf("policy field %q: %v", fe.Path, fe.Err)
+}
+
+func (fe *FieldError) Unwrap() error {
+	return fe.Err
+}
+
+// Parse decodes the JSON encoded policy and validates it.
+func Parse(data []byte) (Policy, error) {
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Policy{}, err
+	}
+	if err := p.Validate(); err != nil {
+		return Policy{}, err
+	}
+	return p, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, rejecting any unknown field
+// and reporting the path of any field that could not be decoded.
+func (p *Policy) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var decoded Policy
+	for _, name := range names {
+		raw := fields[name]
+		var err error
+		switch name {
+		case "attempts":
+			err = json.Unmarshal(raw, &decoded.Attempts)
+		case "strategy":
+			err = json.Unmarshal(raw, &decoded.Strategy)
+		case "base_delay":
+			err = json.Unmarshal(raw, &decoded.BaseDelay)
+		case "max_delay":
+			err = json.Unmarshal(raw, &decoded.MaxDelay)
+		case "multiplier":
+			err = json.Unmarshal(raw, &decoded.Multiplier)
+		case "jitter":
+			err = json.Unmarshal(raw, &decoded.Jitter)
+		case "retryable":
+			err = decodeList(name, raw, func(elem json.RawMessage) error {
+				var s string
+				err := json.Unmarshal(elem, &s)
+				decoded.Retryable = append(decoded.Retryable, s)
+				return err
+			})
+		case "status_codes":
+			err = decodeList(name, raw, func(elem json.RawMessage) error {
+				var code int
+				err := json.Unmarshal(elem, &code)
+				decoded.StatusCodes = append(decoded.StatusCodes, code)
+				return err
+			})
+		case "status_groups":
+			err = decodeList(name, raw, func(elem json.RawMessage) error {
+				var s string
+				err := json.Unmarshal(elem, &s)
+				decoded.StatusGroups = append(decoded.StatusGroups, s)
+				return err
+			})
+		default:
+			err = errors.New(`unknown field`)
+		}
+		if err != nil {
+			var fe *FieldError
+			if errors.As(err, &fe) {
+				return err
+			}
+			return &FieldError{Path: name, Err: err}
+		}
+	}
+	*p = decoded
+	return nil
+}
+
+func decodeList(path string, raw json.RawMessage, decode func(elem json.RawMessage) error) error {
+	var elems []json.RawMessage
+	if err := json.Unmarshal(raw, &elems); err != nil {
+		return err
+	}
+	for i, elem := range elems {
+		if err := decode(elem); err != nil {
+			return &FieldError{Path: fmt.Sprintf("%s[%d]", path, i), Err: err}
+		}
+	}
+	return nil
+}
+
+// Validate checks that every field of the policy is usable,
+// reporting the first invalid field as a FieldError.
+func (p Policy) Validate() error {
+	if p.Attempts < 1 {
+		return &FieldError{Path: "attempts", Err: errors.New(`must be positive`)}
+	}
+	switch p.Strategy {
+	case StrategyNone:
+		if p.BaseDelay != 0 {
+			return &FieldError{Path: "base_delay", Err: errors.New(`must not be set for the none strategy`)}
+		}
+	case StrategyFixed, StrategyExponential:
+		if p.BaseDelay <= 0 {
+			return &FieldError{Path: "base_delay", Err: fmt.Errorf("must be positive for the %s strategy", p.Strategy)}
+		}
+	case "":
+		return &FieldError{Path: "strategy", Err: errors.New(`must be set`)}
+	default:
+		return &FieldError{Path: "strategy", Err: fmt.Errorf("unknown strategy %q", p.Strategy)}
+	}
+	if p.Multiplier != 0 {
+		if p.Strategy != StrategyExponential {
+			return &FieldError{Path: "multiplier", Err: errors.New(`must only be set for the exponential strategy`)}
+		}
+		if p.Multiplier < 1.0 {
+			return &FieldError{Path: "multiplier", Err: errors.New(`must be at least 1.0`)}
+		}
+	}
+	if p.MaxDelay < 0 {
+		return &FieldError{Path: "max_delay", Err: errors.New(`must not be negative`)}
+	}
+	if p.MaxDelay > 0 && p.MaxDelay < p.BaseDelay {
+		return &FieldError{Path: "max_delay", Err: errors.New(`must not be less than base_delay`)}
+	}
+	if p.Jitter < 0 {
+		return &FieldError{Path: "jitter", Err: errors.New(`must not be negative`)}
+	}
+	seen := make(map[string]struct{})
+	for i, class := range p.Retryable {
+		if _, exist := classes[class]; !exist {
+			return &FieldError{Path: fmt.Sprintf("retryable[%d]", i), Err: fmt.Errorf("unknown error class %q", class)}
+		}
+		if _, exist := seen[class]; exist {
+			return &FieldError{Path: fmt.Sprintf("retryable[%d]", i), Err: fmt.Errorf("error class %q already exists", class)}
+		}
+		seen[class] = struct{}{}
+	}
+	codes := make(map[int]struct{})
+	for i, code := range p.StatusCodes {
+		if code < 100 || code > 599 {
+			return &FieldError{Path: fmt.Sprintf("status_codes[%d]", i), Err: fmt.Errorf("invalid status code %d", code)}
+		}
+		if _, exist := codes[code]; exist {
+			return &FieldError{Path: fmt.Sprintf("status_codes[%d]", i), Err: fmt.Errorf("status code %d already exists", code)}
+		}
+		codes[code] = struct{}{}
+	}
+	groups := make(map[int]struct{})
+	for i, group := range p.StatusGroups {
+		g, err := parseGroup(group)
+		if err != nil {
+			return &FieldError{Path: fmt.Sprintf("status_groups[%d]", i), Err: err}
+		}
+		if _, exist := groups[g]; exist {
+			return &FieldError{Path: fmt.Sprintf("status_groups[%d]", i), Err: fmt.Errorf("status group %q already exists", group)}
+		}
+		groups[g] = struct{}{}
+	}
+	return nil
+}
+
+func parseGroup(group string) (int, error) {
+	if len(group) != 3 || group[1:] != "xx" || group[0] < '1' || group[0] > '5' {
+		return 0, fmt.Errorf("invalid status group %q, expected a group such as \"5xx\"", group)
+	}
+	return int(group[0] - '0'), nil
+}
+
+// String summarises the policy using the same values read by FromEnv,
+// such as "attempts=5 backoff=exponential:200ms:2:30s jitter=100ms", which is suitable for logging.
+func (p Policy) String() string {
+	fields := []string{
+		"attempts=" + strconv.Itoa(p.Attempts),
+		"backoff=" + formatStrategy(p),
+	}
+	if p.Strategy == StrategyNone && p.MaxDelay > 0 {
+		fields = append(fields, "max_delay="+time.Duration(p.MaxDelay).String())
+	}
+	if p.Jitter > 0 {
+		fields = append(fields, "jitter="+time.Duration(p.Jitter).String())
+	}
+	if len(p.Retryable) > 0 {
+		fields = append(fields, "retryable="+strings.Join(p.Retryable, ","))
+	}
+	if len(p.StatusCodes) > 0 {
+		codes := make([]string, 0, len(p.StatusCodes))
+		for _, code := range p.StatusCodes {
+			codes = append(codes, strconv.Itoa(code))
+		}
+		fields = append(fields, "status_codes="+strings.Join(codes, ","))
+	}
+	if len(p.StatusGroups) > 0 {
+		fields = append(fields, "status_groups="+strings.Join(p.StatusGroups, ","))
+	}
+	return strings.Join(fields, " ")
+}
+
+// Options validates the policy and returns the equivalent retry options.
+func (p Policy) Options() ([]retry.Option, error) {
+	opts, err := p.delays()
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Retryable) > 0 {
+		opts = append(opts, retry.WithAbortOn(func(err error) bool {
+			return !p.retryable(err)
+		}))
+	}
+	return opts, nil
+}
+
+// Retryer validates the policy and creates a Retryer from it,
+// which is expected to be called with Attempts as the limit.
+func (p Policy) Retryer() (retry.Retryer, error) {
+	opts, err := p.Options()
+	if err != nil {
+		return nil, err
+	}
+	return retry.New(opts...)
+}
+
+// TransportOptions validates the policy and returns the equivalent transport options,
+// which are expected to be used with Attempts as the attempts of the transport.
+// The retryable error classes are not applied since the transport decides
+// which responses are retried using the status codes and groups.
+func (p Policy) TransportOptions() ([]transport.Option, error) {
+	delays, err := p.delays()
+	if err != nil {
+		return nil, err
+	}
+	var opts []transport.Option
+	if len(delays) > 0 {
+		opts = append(opts, transport.WithRetryOptions(delays...))
+	}
+	if len(p.StatusCodes) > 0 {
+		opts = append(opts, transport.WithRetryOnStatusCode(p.StatusCodes...))
+	}
+	if len(p.StatusGroups) > 0 {
+		groups := make([]int, 0, len(p.StatusGroups))
+		for _, group := range p.StatusGroups {
+			g, _ := parseGroup(group)
+			groups = append(groups, g)
+		}
+		opts = append(opts, transport.WithRetryOnStatusGroup(groups...))
+	}
+	return opts, nil
+}
+
+func (p Policy) delays() ([]retry.Option, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	var opts []retry.Option
+	switch p.Strategy {
+	case StrategyFixed:
+		opts = append(opts, retry.WithFixedDelay(time.Duration(p.BaseDelay)))
+	case StrategyExponential:
+		multiplier := p.Multiplier
+		if multiplier == 0 {
+			multiplier = DefaultMultiplier
+		}
+		opts = append(opts, retry.WithGeometricBackoff(time.Duration(p.BaseDelay), multiplier))
+	}
+	if p.Jitter > 0 {
+		opts = append(opts, retry.WithJitter(time.Duration(p.Jitter)))
+	}
+	if p.MaxDelay > 0 {
+		opts = append(opts, retry.WithMaxDelay(time.Duration(p.MaxDelay)))
+	}
+	return opts, nil
+}
+
+func (p Policy) retryable(err error) bool {
+	for _, class := range p.Retryable {
+		if classes[class](err) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package policy_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+	"github.com/MovieStoreGuy/retry/http/transport"
+	"github.com/MovieStoreGuy/retry/policy"
+)
+
+type timeout struct{}
+
+func (timeout) Error() string   { return `timed out` }
+func (timeout) Timeout() bool   { return true }
+func (timeout) Temporary() bool { return true }
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	p, err := policy.Parse([]byte(`{
+		"attempts": 5,
+		"strategy": "exponential",
+		"base_delay": "250ms",
+		"max_delay": "10s",
+		"multiplier": 1.5,
+		"jitter": "100ms",
+		"retryable": ["timeout", "network"],
+		"status_codes": [429],
+		"status_groups": ["5xx"]
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, policy.Policy{
+		Attempts:     5,
+		Strategy:     policy.StrategyExponential,
+		BaseDelay:    policy.Duration(250 * time.Millisecond),
+		MaxDelay:     policy.Duration(10 * time.Second),
+		Multiplier:   1.5,
+		Jitter:       policy.Duration(100 * time.Millisecond),
+		Retryable:    []string{policy.ClassTimeout, policy.ClassNetwork},
+		StatusCodes:  []int{429},
+		StatusGroups: []string{"5xx"},
+	}, p)
+
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+	decoded, err := policy.Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, p, decoded, `Must decode the policy it encoded`)
+}
+
+func TestParseFieldPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		data string
+		path string
+	}{
+		{data: `{"attempts": "3", "strategy": "none"}`, path: `attempts`},
+		{data: `{"attempts": 3, "strategy": "none", "retires": 2}`, path: `retires`},
+		{data: `{"attempts": 3, "strategy": "fixed", "base_delay": 250}`, path: `base_delay`},
+		{data: `{"attempts": 3, "strategy": "fixed", "base_delay": "quarter second"}`, path: `base_delay`},
+		{data: `{"attempts": 3, "strategy": "none", "retryable": ["timeout", 7]}`, path: `retryable[1]`},
+		{data: `{"attempts": 3, "strategy": "none", "status_codes": [500, "502"]}`, path: `status_codes[1]`},
+		{data: `{"attempts": 0, "strategy": "none"}`, path: `attempts`},
+		{data: `{"attempts": 3}`, path: `strategy`},
+		{data: `{"attempts": 3, "strategy": "linear"}`, path: `strategy`},
+		{data: `{"attempts": 3, "strategy": "fixed"}`, path: `base_delay`},
+		{data: `{"attempts": 3, "strategy": "none", "base_delay": "1s"}`, path: `base_delay`},
+		{data: `{"attempts": 3, "strategy": "fixed", "base_delay": "1s", "multiplier": 2}`, path: `multiplier`},
+		{data: `{"attempts": 3, "strategy": "exponential", "base_delay": "1s", "multiplier": 0.5}`, path: `multiplier`},
+		{data: `{"attempts": 3, "strategy": "fixed", "base_delay": "1s", "max_delay": "500ms"}`, path: `max_delay`},
+		{data: `{"attempts": 3, "strategy": "none", "jitter": "-1s"}`, path: `jitter`},
+		{data: `{"attempts": 3, "strategy": "none", "retryable": ["network", "cosmic rays"]}`, path: `retryable[1]`},
+		{data: `{"attempts": 3, "strategy": "none", "retryable": ["network", "network"]}`, path: `retryable[1]`},
+		{data: `{"attempts": 3, "strategy": "none", "status_codes": [500, 600]}`, path: `status_codes[1]`},
+		{data: `{"attempts": 3, "strategy": "none", "status_groups": ["4xx", "50x"]}`, path: `status_groups[1]`},
+		{data: `{"attempts": 3, "strategy": "none", "status_groups": ["5xx", "5xx"]}`, path: `status_groups[1]`},
+	}
+	for _, test := range tests {
+		_, err := policy.Parse([]byte(test.data))
+		var fe *policy.FieldError
+		if assert.True(t, errors.As(err, &fe), `Must report a field error for %s`, test.data) {
+			assert.Equal(t, test.path, fe.Path, `Must report the path of the invalid field for %s`, test.data)
+		}
+	}
+
+	_, err := policy.Parse([]byte(`[]`))
+	assert.Error(t, err, `Must not allow a policy that is not an object`)
+}
+
+func TestPolicyRetryer(t *testing.T) {
+	t.Parallel()
+
+	p := policy.Policy{
+		Attempts:  4,
+		Strategy:  policy.StrategyFixed,
+		BaseDelay: policy.Duration(time.Millisecond),
+		Retryable: []string{policy.ClassTimeout},
+	}
+	r, err := p.Retryer()
+	require.NoError(t, err)
+	assert.Equal(t, time.Millisecond, retry.Backoff(r, 1, p.Attempts))
+
+	attempts := 0
+	err = r.Do(p.Attempts, func() error {
+		attempts++
+		return timeout{}
+	})
+	assert.True(t, retry.HasExceeded(err), `Must retry errors of a retryable class`)
+	assert.Equal(t, p.Attempts, attempts)
+
+	attempts = 0
+	err = r.Do(p.Attempts, func() error {
+		attempts++
+		return errors.New(`bad request`)
+	})
+	assert.True(t, retry.HasAborted(err), `Must abort errors that are not of a retryable class`)
+	assert.Equal(t, 1, attempts)
+
+	p = policy.Policy{
+		Attempts:  4,
+		Strategy:  policy.StrategyExponential,
+		BaseDelay: policy.Duration(time.Second),
+		MaxDelay:  policy.Duration(3 * time.Second),
+	}
+	r, err = p.Retryer()
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, retry.Backoff(r, 1, p.Attempts), `Must wait the base delay before the first retry`)
+	assert.Equal(t, 2*time.Second, retry.Backoff(r, 2, p.Attempts), `Must default the multiplier`)
+	assert.Equal(t, 3*time.Second, retry.Backoff(r, 3, p.Attempts), `Must cap the delay by the max delay`)
+
+	p = policy.Policy{
+		Attempts:   6,
+		Strategy:   policy.StrategyExponential,
+		BaseDelay:  policy.Duration(200 * time.Millisecond),
+		Multiplier: 1.5,
+	}
+	r, err = p.Retryer()
+	require.NoError(t, err)
+	for attempt, expect := range []time.Duration{
+		200 * time.Millisecond,
+		300 * time.Millisecond,
+		450 * time.Millisecond,
+		675 * time.Millisecond,
+		1012500 * time.Microsecond,
+	} {
+		assert.Equal(t, expect, retry.Backoff(r, attempt+1, p.Attempts), `Delay after attempt %d must grow exponentially`, attempt+1)
+	}
+
+	_, err = policy.Policy{Strategy: policy.StrategyNone}.Retryer()
+	assert.Error(t, err, `Must validate the policy`)
+
+	err = r.DoWithContext(context.Background(), 1, func() error { return nil })
+	assert.NoError(t, err)
+}
+
+func TestPolicyTransportOptions(t *testing.T) {
+	t.Parallel()
+
+	var called int64
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&called, 1) < 3 {
+			http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	p, err := policy.Parse([]byte(`{
+		"attempts": 3,
+		"strategy": "fixed",
+		"base_delay": "1ms",
+		"retryable": ["timeout"],
+		"status_groups": ["5xx"]
+	}`))
+	require.NoError(t, err)
+
+	opts, err := p.TransportOptions()
+	require.NoError(t, err)
+	rt, err := transport.Default(p.Attempts, opts...)
+	require.NoError(t, err)
+
+	resp, err := (&http.Client{Transport: rt}).Get(s.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int64(3), atomic.LoadInt64(&called), `Must retry the configured status groups`)
+
+	_, err = policy.Policy{}.TransportOptions()
+	assert.Error(t, err, `Must validate the policy`)
+}
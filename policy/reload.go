@@ -0,0 +1,176 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+// loaded is a policy read from the file with the Retryer created from it.
+type loaded struct {
+	policy  Policy
+	retryer retry.Retryer
+}
+
+// Reloader is a Retryer that loads its policy from a JSON file, polling the modification time
+// of the file and swapping in the new policy once it changes. Each call keeps using the policy
+// that was current when it started, so calls in flight are not affected by a reload.
+// A new file that can not be loaded is reported to the error callback and the last good policy remains in use.
+// Calls made through the Reloader use the limit passed to them, Current returns the Retryer along
+// with its policy so a call can use the Attempts of the policy without a reload mixing the
+// attempts of one policy with the delays of another.
+type Reloader struct {
+	path    string
+	opts    []retry.Option
+	onError func(err error)
+
+	current  atomic.Value
+	modified time.Time
+	size     int64
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+var _ retry.Retryer = (*Reloader)(nil)
+
+// Watch loads the policy from the file at path and checks it for changes every interval,
+// any invalid file after the first is passed to onError.
+// The options are applied after the options of each loaded policy, such as a shared circuit breaker.
+// An error is returned if the first policy can not be loaded.
+func Watch(path string, interval time.Duration, onError func(err error), opts ...retry.Option) (*Reloader, error) {
+	if interval <= 0 {
+		return nil, errors.New(`interval must be a positive value`)
+	}
+	if onError == nil {
+		return nil, errors.New(`error callback must not be nil`)
+	}
+	r := &Reloader{
+		path:    path,
+		opts:    opts,
+		onError: onError,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(info); err != nil {
+		return nil, err
+	}
+	go r.poll(interval)
+	return r, nil
+}
+
+func (r *Reloader) poll(interval time.Duration) {
+	defer close(r.done)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-t.C:
+		}
+		info, err := os.Stat(r.path)
+		if err != nil {
+			r.onError(err)
+			continue
+		}
+		if info.ModTime().Equal(r.modified) && info.Size() == r.size {
+			continue
+		}
+		if err := r.load(info); err != nil {
+			r.onError(err)
+		}
+	}
+}
+
+// load reads the policy from the file, only replacing the current policy if it is valid.
+// The modification time is recorded either way so an invalid file is only reported once.
+func (r *Reloader) load(info os.FileInfo) error {
+	r.modified, r.size = info.ModTime(), info.Size()
+
+	data, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	opts, err := p.Options()
+	if err != nil {
+		return err
+	}
+	rt, err := retry.New(append(opts, r.opts...)...)
+	if err != nil {
+		return err
+	}
+	r.current.Store(&loaded{policy: p, retryer: rt})
+	return nil
+}
+
+func (r *Reloader) latest() *loaded {
+	return r.current.Load().(*loaded)
+}
+
+// Policy returns the policy currently in use, such as for logging.
+func (r *Reloader) Policy() Policy {
+	return r.latest().policy
+}
+
+// Current returns the Retryer created from the policy currently in use along with the policy,
+// which is not affected by any later reload.
+func (r *Reloader) Current() (retry.Retryer, Policy) {
+	l := r.latest()
+	return l.retryer, l.policy
+}
+
+// Close stops watching the file, the last loaded policy remains in use.
+func (r *Reloader) Close() error {
+	r.once.Do(func() { close(r.stop) })
+	<-r.done
+	return nil
+}
+
+func (r *Reloader) Do(limit int, f func() error) error {
+	l := r.latest()
+	return l.retryer.Do(limit, f)
+}
+
+func (r *Reloader) DoWithContext(ctx context.Context, limit int, f func() error) error {
+	l := r.latest()
+	return l.retryer.DoWithContext(ctx, limit, f)
+}
+
+func (r *Reloader) DoWithContextFunc(ctx context.Context, limit int, f func(ctx context.Context) error) error {
+	l := r.latest()
+	return retry.DoWithContextFunc(ctx, l.retryer, limit, f)
+}
+
+func (r *Reloader) DoAsync(ctx context.Context, limit int, f func(ctx context.Context) error) *retry.Future {
+	l := r.latest()
+	return retry.DoAsync(ctx, l.retryer, limit, f)
+}
+
+// DoKeyed shares the attempts between callers using the same key and the same policy,
+// a caller that starts after a reload does not join attempts made with the previous policy.
+func (r *Reloader) DoKeyed(ctx context.Context, key string, limit int, f func(ctx context.Context) error) error {
+	l := r.latest()
+	return retry.DoKeyed(ctx, l.retryer, key, limit, f)
+}
+
+func (r *Reloader) Backoff(attempt, limit int) time.Duration {
+	l := r.latest()
+	return retry.Backoff(l.retryer, attempt, limit)
+}
@@ -0,0 +1,179 @@
+package policy_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+	"github.com/MovieStoreGuy/retry/policy"
+)
+
+// errorLog collects the errors reported by the Reloader.
+type errorLog struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (l *errorLog) report(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errs = append(l.errs, err)
+}
+
+func (l *errorLog) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.errs)
+}
+
+// writePolicy replaces the policy file with a modification time in the future
+// so that every write is seen as a change, renaming it into place so that
+// a partially written file is never read.
+func writePolicy(t *testing.T, path, data string) {
+	tmp := path + ".tmp"
+	require.NoError(t, ioutil.WriteFile(tmp, []byte(data), 0o600))
+	mod := time.Now().Add(time.Duration(len(data)) * time.Second)
+	require.NoError(t, os.Chtimes(tmp, mod, mod))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+func TestInvalidWatch(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "policy")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "policy.json")
+	report := func(error) {}
+
+	_, err = policy.Watch(path, time.Millisecond, report)
+	assert.Error(t, err, `Must not allow a missing file`)
+
+	writePolicy(t, path, `{"attempts": 0, "strategy": "none"}`)
+	_, err = policy.Watch(path, time.Millisecond, report)
+	assert.Error(t, err, `Must not allow an invalid first policy`)
+
+	writePolicy(t, path, `{"attempts": 3, "strategy": "none"}`)
+	_, err = policy.Watch(path, 0, report)
+	assert.Error(t, err, `Must not allow a non positive interval`)
+	_, err = policy.Watch(path, time.Millisecond, nil)
+	assert.Error(t, err, `Must not allow a nil error callback`)
+	_, err = policy.Watch(path, time.Millisecond, report, retry.WithFixedDelay(0))
+	assert.Error(t, err, `Must validate the additional options`)
+}
+
+func TestReloader(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "policy")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "policy.json")
+	writePolicy(t, path, `{"attempts": 3, "strategy": "fixed", "base_delay": "1ms"}`)
+
+	var errs errorLog
+	r, err := policy.Watch(path, 5*time.Millisecond, errs.report)
+	require.NoError(t, err)
+	defer r.Close()
+
+	assert.Equal(t, 3, r.Policy().Attempts)
+	assert.Equal(t, time.Millisecond, retry.Backoff(r, 1, 3))
+
+	writePolicy(t, path, `{"attempts": 5, "strategy": "fixed", "base_delay": "2ms", "retryable": ["timeout"]}`)
+	require.Eventually(t, func() bool {
+		return retry.Backoff(r, 1, 3) == 2*time.Millisecond
+	}, time.Second, time.Millisecond, `Must swap in the changed policy`)
+	assert.Equal(t, 5, r.Policy().Attempts)
+
+	current, p := r.Current()
+	attempts := 0
+	err = current.Do(p.Attempts, func() error {
+		attempts++
+		return timeout{}
+	})
+	assert.True(t, retry.HasExceeded(err))
+	assert.Equal(t, 5, attempts, `Must use the attempts of the reloaded policy`)
+
+	err = r.Do(3, func() error { return errors.New(`bad request`) })
+	assert.True(t, retry.HasAborted(err), `Must apply the reloaded policy`)
+
+	attempts = 0
+	err = r.Do(0, func() error {
+		attempts++
+		return nil
+	})
+	assert.True(t, retry.HasExceeded(err), `Must not make any attempts without a positive limit`)
+	assert.Equal(t, 0, attempts)
+
+	writePolicy(t, path, `{"attempts": 5, "strategy": "linear"}`)
+	require.Eventually(t, func() bool {
+		return errs.len() == 1
+	}, time.Second, time.Millisecond, `Must report an invalid policy`)
+	assert.Equal(t, 2*time.Millisecond, retry.Backoff(r, 1, 3), `Must keep the last good policy`)
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, errs.len(), `Must only report an invalid policy once`)
+
+	writePolicy(t, path, `{"attempts": 2, "strategy": "none"}`)
+	require.Eventually(t, func() bool {
+		return r.Policy().Attempts == 2
+	}, time.Second, time.Millisecond, `Must recover once the policy is valid`)
+
+	require.NoError(t, r.Close())
+	writePolicy(t, path, `{"attempts": 4, "strategy": "none"}`)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 2, r.Policy().Attempts, `Must not reload once closed`)
+}
+
+func TestReloaderInFlight(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "policy")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "policy.json")
+	writePolicy(t, path, `{"attempts": 3, "strategy": "fixed", "base_delay": "1ms"}`)
+
+	r, err := policy.Watch(path, time.Millisecond, func(err error) { t.Error(err) })
+	require.NoError(t, err)
+	defer r.Close()
+
+	var (
+		started  = make(chan struct{})
+		reloaded = make(chan struct{})
+		attempts int
+	)
+	fu := retry.DoAsync(context.Background(), r, 3, func(context.Context) error {
+		if attempts++; attempts == 1 {
+			close(started)
+			<-reloaded
+		}
+		return errors.New(`bad request`)
+	})
+
+	<-started
+	writePolicy(t, path, `{"attempts": 5, "strategy": "fixed", "base_delay": "1ms", "retryable": ["timeout"]}`)
+	require.Eventually(t, func() bool {
+		return len(r.Policy().Retryable) == 1
+	}, time.Second, time.Millisecond)
+	close(reloaded)
+
+	err = fu.Wait(context.Background())
+	assert.True(t, retry.HasExceeded(err), `Must keep the policy the call started with`)
+	assert.Equal(t, 3, attempts)
+
+	err = retry.DoKeyed(context.Background(), r, `key`, 3, func(context.Context) error { return errors.New(`bad request`) })
+	assert.True(t, retry.HasAborted(err), `Must use the reloaded policy for new calls`)
+}
@@ -0,0 +1,93 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseStrategy sets the strategy of the policy from a spec of the form
+//
+//	none
+//	fixed:<delay>[:<max delay>]
+//	exponential:<base delay>[:<multiplier>[:<max delay>]]
+//
+// such as "exponential:200ms:2.0:30s".
+func parseStrategy(spec string, p *Policy) error {
+	parts := strings.Split(spec, ":")
+	name, args := parts[0], parts[1:]
+
+	var (
+		base, max  time.Duration
+		multiplier float64
+		err        error
+	)
+	switch name {
+	case StrategyNone:
+		if len(args) != 0 {
+			return fmt.Errorf("strategy %q takes no arguments in %q", name, spec)
+		}
+	case StrategyFixed:
+		if len(args) < 1 || len(args) > 2 {
+			return fmt.Errorf("expected fixed:<delay>[:<max delay>], got %q", spec)
+		}
+		if base, err = time.ParseDuration(args[0]); err != nil {
+			return fmt.Errorf("invalid delay %q in %q", args[0], spec)
+		}
+		if len(args) == 2 {
+			if max, err = time.ParseDuration(args[1]); err != nil {
+				return fmt.Errorf("invalid max delay %q in %q", args[1], spec)
+			}
+		}
+	case StrategyExponential:
+		if len(args) < 1 || len(args) > 3 {
+			return fmt.Errorf("expected exponential:<base delay>[:<multiplier>[:<max delay>]], got %q", spec)
+		}
+		if base, err = time.ParseDuration(args[0]); err != nil {
+			return fmt.Errorf("invalid base delay %q in %q", args[0], spec)
+		}
+		if len(args) >= 2 {
+			if multiplier, err = strconv.ParseFloat(args[1], 64); err != nil {
+				return fmt.Errorf("invalid multiplier %q in %q", args[1], spec)
+			}
+		}
+		if len(args) == 3 {
+			if max, err = time.ParseDuration(args[2]); err != nil {
+				return fmt.Errorf("invalid max delay %q in %q", args[2], spec)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown strategy %q in %q", name, spec)
+	}
+
+	p.Strategy = name
+	p.BaseDelay = Duration(base)
+	p.Multiplier = multiplier
+	p.MaxDelay = Duration(max)
+	return nil
+}
+
+// formatStrategy returns the spec of the strategy of the policy that is read by parseStrategy.
+func formatStrategy(p Policy) string {
+	switch p.Strategy {
+	case StrategyFixed:
+		spec := StrategyFixed + ":" + time.Duration(p.BaseDelay).String()
+		if p.MaxDelay > 0 {
+			spec += ":" + time.Duration(p.MaxDelay).String()
+		}
+		return spec
+	case StrategyExponential:
+		multiplier := p.Multiplier
+		if multiplier == 0 {
+			multiplier = DefaultMultiplier
+		}
+		spec := StrategyExponential + ":" + time.Duration(p.BaseDelay).String() +
+			":" + strconv.FormatFloat(multiplier, 'f', -1, 64)
+		if p.MaxDelay > 0 {
+			spec += ":" + time.Duration(p.MaxDelay).String()
+		}
+		return spec
+	}
+	return p.Strategy
+}
@@ -0,0 +1,136 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+type rejectAll struct{}
+
+func (rejectAll) Allow() (func(error), error) {
+	return nil, errors.New(`open`)
+}
+
+// must returns the created policy, failing the test if it could not be created.
+func must(t *testing.T) func(p retry.Policy, err error) retry.Policy {
+	return func(p retry.Policy, err error) retry.Policy {
+		require.NoError(t, err)
+		return p
+	}
+}
+
+func TestInvalidWrap(t *testing.T) {
+	t.Parallel()
+
+	_, err := retry.Wrap()
+	assert.Error(t, err, `Must not allow no policies`)
+
+	for _, build := range []func() (retry.Policy, error){
+		func() (retry.Policy, error) { return retry.RetryPolicy(nil, 1) },
+		func() (retry.Policy, error) { return retry.TimeoutPolicy(0) },
+		func() (retry.Policy, error) { return retry.BreakerPolicy(nil) },
+		func() (retry.Policy, error) { return retry.BulkheadPolicy(nil) },
+		func() (retry.Policy, error) { return retry.RateLimitPolicy(nil) },
+		func() (retry.Policy, error) { return retry.FallbackPolicy(nil) },
+	} {
+		p, err := build()
+		assert.Error(t, err, `Must not allow an invalid policy`)
+		assert.Nil(t, p)
+	}
+
+	_, err = retry.Wrap(must(t)(retry.TimeoutPolicy(time.Second)), nil)
+	assert.Error(t, err, `Must not allow a nil policy`)
+
+	p, err := retry.Wrap(must(t)(retry.RetryPolicy(retry.Must(), 1)))
+	require.NoError(t, err)
+	assert.Error(t, p.Execute(nil, func(context.Context) error { return nil }))
+	assert.Error(t, p.Execute(context.Background(), nil))
+}
+
+func TestWrapOrder(t *testing.T) {
+	t.Parallel()
+
+	var order []string
+	trace := func(name string) retry.Policy {
+		return retry.PolicyFunc(func(ctx context.Context, f func(ctx context.Context) error) error {
+			order = append(order, name)
+			return f(ctx)
+		})
+	}
+
+	p, err := retry.Wrap(trace(`outer`), must(t)(retry.RetryPolicy(retry.Must(), 2)), trace(`inner`))
+	require.NoError(t, err)
+
+	called := 0
+	err = p.Execute(context.Background(), func(context.Context) error {
+		if called++; called < 2 {
+			return errors.New(`discard`)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`outer`, `inner`, `inner`}, order, `Policies must be applied in the declared order`)
+}
+
+func TestWrapComposition(t *testing.T) {
+	t.Parallel()
+
+	b, err := retry.NewBulkhead(1, 0, 0)
+	require.NoError(t, err)
+	l, err := retry.NewRateLimiter(1000, 10)
+	require.NoError(t, err)
+
+	var final error
+	p, err := retry.Wrap(
+		must(t)(retry.FallbackPolicy(func(_ context.Context, err error) error {
+			final = err
+			return nil
+		})),
+		must(t)(retry.TimeoutPolicy(time.Second)),
+		must(t)(retry.RetryPolicy(retry.Must(), 5)),
+		must(t)(retry.RateLimitPolicy(l)),
+		must(t)(retry.BulkheadPolicy(b)),
+		must(t)(retry.BreakerPolicy(rejectAll{})),
+	)
+	require.NoError(t, err)
+
+	called := 0
+	err = p.Execute(context.Background(), func(context.Context) error {
+		called++
+		return nil
+	})
+	assert.True(t, retry.HasFallenBack(err), `Fallback must serve the result`)
+	assert.True(t, retry.HasAborted(final), `Open breaker must not be retried`)
+	assert.Equal(t, 0, called)
+	assert.Equal(t, 0, b.Running(), `Bulkhead must be released`)
+
+	p, err = retry.Wrap(must(t)(retry.TimeoutPolicy(10*time.Millisecond)), must(t)(retry.BulkheadPolicy(b)))
+	require.NoError(t, err)
+	err = p.Execute(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.Equal(t, context.DeadlineExceeded, err, `Timeout must cancel the function`)
+}
+
+func TestBreakerPolicyPanic(t *testing.T) {
+	t.Parallel()
+
+	cb := &countingBreaker{}
+	p := must(t)(retry.BreakerPolicy(cb))
+	assert.Panics(t, func() {
+		_ = p.Execute(context.Background(), func(context.Context) error {
+			panic(`boom`)
+		})
+	})
+	results := cb.recorded()
+	require.Len(t, results, 1, `Panicking function must release the breaker`)
+	assert.Error(t, results[0], `Panic must be reported as failed`)
+}
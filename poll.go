@@ -0,0 +1,83 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNotReady is wrapped by ExceededRetries when the condition
+// passed to WaitUntil was never done within the attempts allowed.
+// An attempt that returns ErrNotReady is retried without being treated as a failed attempt,
+// so it is not passed to any observers, is passed to a CircuitBreaker as a success,
+// does not spend any permits such as a RetryBudget, and is not written to the dead letter.
+var ErrNotReady = errors.New(`condition is not ready`)
+
+// notReady reports if the attempt found the work was not yet ready,
+// which says nothing about the health of what was attempted.
+func notReady(err error) bool {
+	return errors.Is(err, ErrNotReady)
+}
+
+// Poll configures how WaitUntil checks the condition.
+type Poll struct {
+	// Timeout limits how long to wait for the condition, zero only relies on the context.
+	Timeout time.Duration
+	// Progress is optionally called after each check where the condition was not done,
+	// with the number of checks made and the time elapsed so far.
+	Progress func(attempt int, elapsed time.Duration)
+}
+
+// WaitUntil checks the condition until it is done, waiting between each check with the
+// delays configured on the Retryer. A condition that is not done is checked again, where
+// as an error returned by the condition stops any further checks and is returned.
+// Checks that are not done are not treated as failed attempts by the options of the Retryer,
+// as described by ErrNotReady, so polling does not trip a circuit breaker or spend a retry budget.
+// If the condition was never done, ErrNotReady is returned wrapped by ExceededRetries,
+// or the context error if the timeout was reached first.
+func WaitUntil(ctx context.Context, r Retryer, limit int, p Poll, cond func(ctx context.Context) (bool, error)) error {
+	if r == nil {
+		return errors.New(`retryer is nil`)
+	}
+	if cond == nil {
+		return errors.New(`invalid function provided`)
+	}
+	if p.Timeout < 0 {
+		return errors.New(`timeout must not be negative`)
+	}
+	if ctx == nil {
+		return errors.New(`invalid context provided`)
+	}
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	var (
+		start = time.Now()
+		// Counted atomically since a hedging Retryer makes checks concurrently
+		checks int64
+	)
+	err := DoWithContextFunc(ctx, r, limit, func(ctx context.Context) error {
+		attempt := int(atomic.AddInt64(&checks, 1))
+		done, err := cond(ctx)
+		if err != nil {
+			return AbortedRetries(err)
+		}
+		if !done {
+			if p.Progress != nil {
+				p.Progress(attempt, time.Since(start))
+			}
+			return ErrNotReady
+		}
+		return nil
+	})
+	if HasAborted(err) && !errors.Is(err, ErrNotReady) {
+		// Return the error from the condition as is,
+		// since it was only marked to stop any further checks
+		return errors.Unwrap(err)
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+func TestInvalidWaitUntil(t *testing.T) {
+	t.Parallel()
+
+	ready := func(context.Context) (bool, error) { return true, nil }
+
+	assert.Error(t, retry.WaitUntil(context.Background(), nil, 1, retry.Poll{}, ready), `Must not allow a nil retryer`)
+	assert.Error(t, retry.WaitUntil(context.Background(), retry.Must(), 1, retry.Poll{}, nil), `Must not allow a nil condition`)
+	assert.Error(t, retry.WaitUntil(context.Background(), retry.Must(), 1, retry.Poll{Timeout: -time.Second}, ready), `Must not allow a negative timeout`)
+	assert.Error(t, retry.WaitUntil(nil, retry.Must(), 1, retry.Poll{}, ready), `Must not allow a nil context`)
+}
+
+func TestWaitUntilReady(t *testing.T) {
+	t.Parallel()
+
+	var (
+		checks   int
+		progress []int
+	)
+	err := retry.WaitUntil(context.Background(), retry.Must(retry.WithFixedDelay(time.Millisecond)), 5, retry.Poll{
+		Progress: func(attempt int, elapsed time.Duration) {
+			progress = append(progress, attempt)
+			assert.Greater(t, int64(elapsed), int64(0))
+		},
+	}, func(context.Context) (bool, error) {
+		checks++
+		return checks == 3, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, checks)
+	assert.Equal(t, []int{1, 2}, progress, `Must report progress for each check that was not ready`)
+}
+
+func TestWaitUntilNotReady(t *testing.T) {
+	t.Parallel()
+
+	notReady := func(context.Context) (bool, error) { return false, nil }
+
+	err := retry.WaitUntil(context.Background(), retry.Must(), 3, retry.Poll{}, notReady)
+	assert.True(t, retry.HasExceeded(err))
+	assert.True(t, errors.Is(err, retry.ErrNotReady), `Must report the condition was never ready`)
+
+	err = retry.WaitUntil(context.Background(), retry.Must(retry.WithFixedDelay(5*time.Millisecond)), 1000, retry.Poll{
+		Timeout: 20 * time.Millisecond,
+	}, notReady)
+	assert.Equal(t, context.DeadlineExceeded, err, `Must stop once the timeout is reached`)
+
+	failed := errors.New(`job failed`)
+	checks := 0
+	err = retry.WaitUntil(context.Background(), retry.Must(), 3, retry.Poll{}, func(context.Context) (bool, error) {
+		checks++
+		return false, failed
+	})
+	assert.Equal(t, failed, err, `Errors must not be treated as not ready`)
+	assert.Equal(t, 1, checks)
+}
+
+func TestWaitUntilNotReadyIsNotAFailure(t *testing.T) {
+	t.Parallel()
+
+	b, err := retry.NewRetryBudget(1, 0.5)
+	require.NoError(t, err)
+	var (
+		cb = &countingBreaker{}
+		dl = retry.NewMemoryDeadLetter()
+		r  = retry.Must(retry.WithCircuitBreaker(cb), retry.WithRetryBudget(b), retry.WithDeadLetter(dl))
+	)
+
+	checks := 0
+	err = retry.WaitUntil(context.Background(), r, 5, retry.Poll{}, func(context.Context) (bool, error) {
+		checks++
+		return checks == 4, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []error{nil, nil, nil, nil}, cb.recorded(), `Checks that are not ready must not fail the breaker`)
+	assert.Equal(t, 1.0, b.Tokens(), `Checks that are not ready must not spend the budget`)
+
+	err = retry.WaitUntil(context.Background(), r, 3, retry.Poll{}, func(context.Context) (bool, error) {
+		return false, nil
+	})
+	assert.True(t, errors.Is(err, retry.ErrNotReady))
+	letters, err := dl.List(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, letters, `Conditions that were never ready must not be written to the dead letter`)
+}
@@ -0,0 +1,102 @@
+package queue
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/MovieStoreGuy/retry/internal/journal"
+)
+
+const (
+	opPut    = "put"
+	opDelete = "del"
+	// opLast records the highest job ID that has been assigned,
+	// so IDs are not reused once their jobs are compacted away.
+	opLast = "last"
+)
+
+// record is a single line of the append only journal,
+// where the latest record for a job replaces any before it.
+type record struct {
+	Op  string `json:"op"`
+	ID  uint64 `json:"id"`
+	Job *Job   `json:"job,omitempty"`
+}
+
+type jobJournal struct {
+	path    string
+	file    *os.File
+	records int
+	// last is the highest job ID that has been written to the journal.
+	last uint64
+}
+
+// openJournal replays the journal at path into the returned jobs,
+// and opens it ready to have further records appended.
+func openJournal(path string) (*jobJournal, map[uint64]*Job, error) {
+	var (
+		jobs = make(map[uint64]*Job)
+		last uint64
+	)
+	f, records, err := journal.Open(path, func(line []byte) error {
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return err
+		}
+		switch rec.Op {
+		case opPut:
+			if rec.Job != nil {
+				jobs[rec.ID] = rec.Job
+			}
+		case opDelete:
+			delete(jobs, rec.ID)
+		}
+		if rec.ID > last {
+			last = rec.ID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &jobJournal{path: path, file: f, records: records, last: last}, jobs, nil
+}
+
+func (j *jobJournal) put(job *Job) error {
+	return j.append(record{Op: opPut, ID: job.ID, Job: job})
+}
+
+func (j *jobJournal) delete(id uint64) error {
+	return j.append(record{Op: opDelete, ID: id})
+}
+
+func (j *jobJournal) append(rec record) error {
+	if err := journal.Append(j.file, rec); err != nil {
+		return err
+	}
+	j.records++
+	if rec.ID > j.last {
+		j.last = rec.ID
+	}
+	return nil
+}
+
+// compact rewrites the journal to only contain the live jobs and the highest
+// assigned ID, replacing the existing journal once it has safely been written.
+func (j *jobJournal) compact(jobs map[uint64]*Job) error {
+	records := []interface{}{record{Op: opLast, ID: j.last}}
+	for id, job := range jobs {
+		records = append(records, record{Op: opPut, ID: id, Job: job})
+	}
+	f, err := journal.Rewrite(j.path, records)
+	if err != nil {
+		return err
+	}
+	j.file.Close()
+	j.file, j.records = f, len(records)
+	return nil
+}
+
+func (j *jobJournal) close() error {
+	return j.file.Close()
+}
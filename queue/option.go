@@ -0,0 +1,84 @@
+package queue
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+// Option allows for the queue to be configured when it is opened.
+type Option func(q *Queue) error
+
+// WithWorkers sets the number of jobs that are run at once.
+func WithWorkers(n int) Option {
+	return func(q *Queue) error {
+		if n < 1 {
+			return errors.New(`workers must be positive`)
+		}
+		q.workers = n
+		return nil
+	}
+}
+
+// WithPollInterval sets the longest time the queue waits
+// before checking for jobs that are due to run.
+func WithPollInterval(d time.Duration) Option {
+	return func(q *Queue) error {
+		if d <= 0 {
+			return errors.New(`poll interval must be a positive value`)
+		}
+		q.poll = d
+		return nil
+	}
+}
+
+// WithCompactThreshold compacts the journal once it holds more than
+// the threshold number of records for each job still in the queue.
+func WithCompactThreshold(threshold int) Option {
+	return func(q *Queue) error {
+		if threshold < 1 {
+			return errors.New(`compact threshold must be positive`)
+		}
+		q.threshold = threshold
+		return nil
+	}
+}
+
+// WithExhausted calls the function with each job that has been removed from
+// the queue without succeeding, along with the final error returned for it.
+func WithExhausted(f func(job Job, err error)) Option {
+	return func(q *Queue) error {
+		if f == nil {
+			return errors.New(`exhausted function must not be nil`)
+		}
+		q.exhausted = f
+		return nil
+	}
+}
+
+// WithWriteError calls the function with each job that was updated or removed after an attempt
+// but could not be written to the journal, along with the write error.
+// The queue carries on with the change, but it is lost if the queue is reopened
+// before a later write of the job succeeds, such as a removed job being run again.
+func WithWriteError(f func(job Job, err error)) Option {
+	return func(q *Queue) error {
+		if f == nil {
+			return errors.New(`write error function must not be nil`)
+		}
+		q.writeError = f
+		return nil
+	}
+}
+
+// WithDeadLetter writes each exhausted job to the dead letter,
+// along with the history of every attempt made for it.
+func WithDeadLetter(dl retry.DeadLetter) Option {
+	return func(q *Queue) error {
+		if dl == nil {
+			return errors.New(`dead letter must not be nil`)
+		}
+		q.dead = dl
+		return nil
+	}
+}
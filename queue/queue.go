@@ -0,0 +1,338 @@
+// Package queue implements a durable retry queue, where jobs are persisted
+// to an append only journal so any waiting to be retried survive a restart.
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+// ErrClosed is returned when using a queue that has been closed.
+var ErrClosed = errors.New(`queue has been closed`)
+
+// Policy is persisted with each job to control how it is retried.
+type Policy struct {
+	// Attempts is the limit of attempts made before the job is exhausted.
+	Attempts int `json:"attempts"`
+}
+
+// Job is a unit of work held by the queue.
+type Job struct {
+	ID        uint64          `json:"id"`
+	Payload   []byte          `json:"payload"`
+	Policy    Policy          `json:"policy"`
+	Attempt   int             `json:"attempt"`
+	NextRun   time.Time       `json:"next_run"`
+	LastError string          `json:"last_error,omitempty"`
+	History   []retry.Attempt `json:"history,omitempty"`
+}
+
+// Handler processes the payload of a job, returning an error if it should be retried.
+// Returning an error wrapped by retry.AbortedRetries removes the job without retrying it.
+type Handler func(ctx context.Context, payload []byte) error
+
+// Queue runs the persisted jobs using a pool of workers, waiting between attempts
+// of a job using the delays configured on the Retryer.
+type Queue struct {
+	workers    int
+	poll       time.Duration
+	threshold  int
+	exhausted  func(job Job, err error)
+	writeError func(job Job, err error)
+	dead       retry.DeadLetter
+
+	retryer retry.Retryer
+	handler Handler
+
+	mu      sync.Mutex
+	journal *jobJournal
+	jobs    map[uint64]*Job
+	running map[uint64]struct{}
+	next    uint64
+	closed  bool
+
+	wake   chan struct{}
+	work   chan *Job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Open replays the journal at path, creating it if it does not exist,
+// and starts running the jobs using the handler.
+// Each attempt is made through the Retryer as a single attempt, so any of its
+// options such as circuit breakers or rate limits are applied to the job.
+// The dead letter of the Retryer is not written to by these attempts,
+// exhausted jobs are written to the dead letter set by WithDeadLetter instead.
+func Open(path string, r retry.Retryer, handler Handler, opts ...Option) (*Queue, error) {
+	if r == nil {
+		return nil, errors.New(`retryer is nil`)
+	}
+	if handler == nil {
+		return nil, errors.New(`handler is nil`)
+	}
+	q := &Queue{
+		workers:   1,
+		poll:      time.Second,
+		threshold: 4,
+		retryer:   r,
+		handler:   handler,
+		running:   make(map[uint64]struct{}),
+		wake:      make(chan struct{}, 1),
+		work:      make(chan *Job),
+	}
+	for _, opt := range opts {
+		if err := opt(q); err != nil {
+			return nil, err
+		}
+	}
+
+	j, jobs, err := openJournal(path)
+	if err != nil {
+		return nil, err
+	}
+	q.journal, q.jobs, q.next = j, jobs, j.last
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q.cancel = cancel
+	q.wg.Add(q.workers + 1)
+	for i := 0; i < q.workers; i++ {
+		go q.worker(ctx)
+	}
+	go q.dispatch(ctx)
+	return q, nil
+}
+
+// Enqueue persists the payload as a new job that is run straight away,
+// returning once the job is safely written to the journal.
+func (q *Queue) Enqueue(payload []byte, p Policy) (uint64, error) {
+	if p.Attempts < 1 {
+		return 0, errors.New(`policy attempts must be positive`)
+	}
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return 0, ErrClosed
+	}
+	q.next++
+	job := &Job{
+		ID:      q.next,
+		Payload: append([]byte(nil), payload...),
+		Policy:  p,
+		NextRun: time.Now(),
+	}
+	if err := q.journal.put(job); err != nil {
+		q.mu.Unlock()
+		return 0, err
+	}
+	q.jobs[job.ID] = job
+	q.mu.Unlock()
+
+	q.notify()
+	return job.ID, nil
+}
+
+// Len returns the number of jobs that have not yet succeeded or been exhausted.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
+
+// Jobs returns a copy of the jobs that are held by the queue.
+func (q *Queue) Jobs() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := make([]Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		jobs = append(jobs, *job)
+	}
+	return jobs
+}
+
+// Compact rewrites the journal to only hold the jobs still in the queue.
+func (q *Queue) Compact() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	return q.journal.compact(q.jobs)
+}
+
+// Close stops running jobs, waiting for any attempts in progress to be cancelled,
+// and closes the journal. Jobs that have not finished are run once reopened.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrClosed
+	}
+	q.closed = true
+	q.mu.Unlock()
+
+	q.cancel()
+	q.wg.Wait()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.journal.close()
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+		// Dispatcher has already been woken
+	}
+}
+
+// due returns the jobs that are ready to run, and how long until the next job is due.
+func (q *Queue) due(now time.Time) ([]*Job, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var ready []*Job
+	wait := q.poll
+	for id, job := range q.jobs {
+		if _, exist := q.running[id]; exist {
+			continue
+		}
+		if next := job.NextRun.Sub(now); next > 0 {
+			if next < wait {
+				wait = next
+			}
+			continue
+		}
+		q.running[id] = struct{}{}
+		copied := *job
+		ready = append(ready, &copied)
+	}
+	return ready, wait
+}
+
+func (q *Queue) dispatch(ctx context.Context) {
+	defer q.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-q.wake:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		}
+
+		ready, wait := q.due(time.Now())
+		for i, job := range ready {
+			select {
+			case q.work <- job:
+			case <-ctx.Done():
+				q.mu.Lock()
+				for _, job := range ready[i:] {
+					delete(q.running, job.ID)
+				}
+				q.mu.Unlock()
+				return
+			}
+		}
+		if len(ready) > 0 {
+			wait = 0
+		}
+		timer.Reset(wait)
+	}
+}
+
+func (q *Queue) worker(ctx context.Context) {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-q.work:
+			q.run(ctx, job)
+		}
+	}
+}
+
+func (q *Queue) run(ctx context.Context, job *Job) {
+	started := time.Now()
+	// Each call is only one attempt of the job, so it must not be treated
+	// as the job being given up on by the dead letter of the retryer
+	err := retry.DoWithContextFunc(retry.WithoutDeadLetter(ctx), q.retryer, 1, func(ctx context.Context) error {
+		return q.handler(ctx, job.Payload)
+	})
+	if retry.HasExceeded(err) {
+		// Each run is a single attempt of the retryer,
+		// so only the error returned by the handler is of interest
+		err = errors.Unwrap(err)
+	}
+
+	q.mu.Lock()
+	delete(q.running, job.ID)
+
+	if ctx.Err() != nil {
+		// The queue is closing, the attempt is not counted
+		// so the job is run again once the queue is reopened.
+		q.mu.Unlock()
+		return
+	}
+
+	job.Attempt++
+	attempt := retry.Attempt{Number: job.Attempt, Time: started}
+	if err != nil {
+		job.LastError = err.Error()
+		attempt.Error = job.LastError
+	}
+	job.History = append(job.History, attempt)
+	if err != nil && !retry.HasAborted(err) && job.Attempt < job.Policy.Attempts {
+		job.NextRun = time.Now().Add(retry.Backoff(q.retryer, job.Attempt, job.Policy.Attempts))
+		// The job is updated even if it could not be written,
+		// otherwise it would be run again straight away.
+		q.jobs[job.ID] = job
+		werr := q.journal.put(job)
+		q.mu.Unlock()
+		if werr != nil {
+			q.writeFailed(*job, werr)
+		}
+		// Wake the dispatcher in case the job is due
+		// before it was otherwise going to check
+		q.notify()
+		return
+	}
+
+	werr := q.journal.delete(job.ID)
+	delete(q.jobs, job.ID)
+	if werr == nil && q.journal.records > q.threshold*(len(q.jobs)+1) {
+		_ = q.journal.compact(q.jobs)
+	}
+	q.mu.Unlock()
+	if werr != nil {
+		q.writeFailed(*job, werr)
+	}
+
+	if err != nil && q.dead != nil {
+		_ = q.dead.Put(ctx, retry.NewLetter(job.Payload, err, job.History))
+	}
+	if err != nil && q.exhausted != nil {
+		q.exhausted(*job, err)
+	}
+}
+
+func (q *Queue) writeFailed(job Job, err error) {
+	if q.writeError != nil {
+		q.writeError(job, err)
+	}
+}
@@ -0,0 +1,270 @@
+package queue_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+	"github.com/MovieStoreGuy/retry/queue"
+)
+
+func journalPath(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "queue")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "journal")
+}
+
+func TestInvalidQueue(t *testing.T) {
+	t.Parallel()
+
+	path := journalPath(t)
+	handler := func(context.Context, []byte) error { return nil }
+
+	_, err := queue.Open(path, nil, handler)
+	assert.Error(t, err, `Must not allow a nil retryer`)
+
+	_, err = queue.Open(path, retry.Must(), nil)
+	assert.Error(t, err, `Must not allow a nil handler`)
+
+	invalid := []queue.Option{
+		queue.WithWorkers(0),
+		queue.WithPollInterval(0),
+		queue.WithCompactThreshold(0),
+		queue.WithExhausted(nil),
+		queue.WithWriteError(nil),
+		queue.WithDeadLetter(nil),
+	}
+	for _, opt := range invalid {
+		_, err := queue.Open(path, retry.Must(), handler, opt)
+		assert.Error(t, err)
+	}
+
+	q, err := queue.Open(path, retry.Must(), handler)
+	require.NoError(t, err)
+	_, err = q.Enqueue([]byte(`payload`), queue.Policy{})
+	assert.Error(t, err, `Must not allow a policy without attempts`)
+
+	require.NoError(t, q.Close())
+	assert.Equal(t, queue.ErrClosed, q.Close())
+	_, err = q.Enqueue([]byte(`payload`), queue.Policy{Attempts: 1})
+	assert.Equal(t, queue.ErrClosed, err)
+}
+
+func TestQueueRetriesJobs(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu        sync.Mutex
+		called    = make(map[string]int)
+		exhausted []queue.Job
+	)
+	q, err := queue.Open(journalPath(t), retry.Must(retry.WithFixedDelay(time.Millisecond)), func(_ context.Context, payload []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		called[string(payload)]++
+		switch string(payload) {
+		case `recovers`:
+			if called[`recovers`] < 3 {
+				return errors.New(`discard`)
+			}
+			return nil
+		case `aborts`:
+			return retry.AbortedRetries(errors.New(`doom`))
+		}
+		return errors.New(`discard`)
+	}, queue.WithWorkers(2), queue.WithExhausted(func(job queue.Job, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		exhausted = append(exhausted, job)
+	}))
+	require.NoError(t, err)
+	defer q.Close()
+
+	for _, payload := range []string{`recovers`, `aborts`, `fails`} {
+		_, err := q.Enqueue([]byte(payload), queue.Policy{Attempts: 4})
+		require.NoError(t, err)
+	}
+	assert.Eventually(t, func() bool { return q.Len() == 0 }, 5*time.Second, time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[string]int{`recovers`: 3, `aborts`: 1, `fails`: 4}, called)
+	require.Len(t, exhausted, 2, `Only jobs that did not succeed are exhausted`)
+	for _, job := range exhausted {
+		switch string(job.Payload) {
+		case `aborts`:
+			assert.Equal(t, 1, job.Attempt)
+		case `fails`:
+			assert.Equal(t, 4, job.Attempt)
+			assert.Equal(t, `discard`, job.LastError)
+		}
+	}
+}
+
+func TestQueueReplaysJournal(t *testing.T) {
+	t.Parallel()
+
+	path := journalPath(t)
+	attempted := make(chan struct{}, 1)
+	q, err := queue.Open(path, retry.Must(retry.WithFixedDelay(time.Hour)), func(context.Context, []byte) error {
+		attempted <- struct{}{}
+		return errors.New(`discard`)
+	})
+	require.NoError(t, err)
+
+	id, err := q.Enqueue([]byte(`payload`), queue.Policy{Attempts: 5})
+	require.NoError(t, err)
+	<-attempted
+	assert.Eventually(t, func() bool {
+		jobs := q.Jobs()
+		return len(jobs) == 1 && jobs[0].Attempt == 1
+	}, time.Second, time.Millisecond)
+	require.NoError(t, q.Close())
+
+	// Simulate a crash part way through writing a record
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"op":"del","id":`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	done := make(chan []byte, 1)
+	q, err = queue.Open(path, retry.Must(), func(_ context.Context, payload []byte) error {
+		done <- payload
+		return errors.New(`discard`)
+	}, queue.WithPollInterval(time.Millisecond))
+	require.NoError(t, err)
+	defer q.Close()
+
+	jobs := q.Jobs()
+	require.Len(t, jobs, 1, `Job must survive being reopened`)
+	assert.Equal(t, id, jobs[0].ID)
+	assert.Equal(t, 1, jobs[0].Attempt, `Must keep the attempt count`)
+	assert.True(t, jobs[0].NextRun.After(time.Now().Add(30*time.Minute)), `Must keep the next run time`)
+
+	next, err := q.Enqueue([]byte(`next`), queue.Policy{Attempts: 1})
+	require.NoError(t, err)
+	assert.Greater(t, next, id, `Must not reuse job ids`)
+	assert.Equal(t, []byte(`next`), <-done)
+}
+
+func TestQueueCompaction(t *testing.T) {
+	t.Parallel()
+
+	path := journalPath(t)
+	q, err := queue.Open(path, retry.Must(), func(context.Context, []byte) error {
+		return nil
+	}, queue.WithCompactThreshold(2))
+	require.NoError(t, err)
+
+	var last uint64
+	for i := 0; i < 20; i++ {
+		last, err = q.Enqueue([]byte(`payload`), queue.Policy{Attempts: 1})
+		require.NoError(t, err)
+	}
+	assert.Eventually(t, func() bool { return q.Len() == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, q.Compact())
+	require.NoError(t, q.Close())
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, bytes.Count(data, []byte("\n")), `Compacted journal must only hold live jobs`)
+
+	q, err = queue.Open(path, retry.Must(), func(context.Context, []byte) error {
+		return nil
+	})
+	require.NoError(t, err)
+	defer q.Close()
+
+	assert.Equal(t, 0, q.Len())
+	next, err := q.Enqueue([]byte(`payload`), queue.Policy{Attempts: 1})
+	require.NoError(t, err)
+	assert.Greater(t, next, last, `Must not reuse the ids of compacted jobs`)
+}
+
+func TestQueueDeadLetter(t *testing.T) {
+	t.Parallel()
+
+	dl := retry.NewMemoryDeadLetter()
+	fail := true
+	var mu sync.Mutex
+	q, err := queue.Open(journalPath(t), retry.Must(), func(context.Context, []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			return errors.New(`discard`)
+		}
+		return nil
+	}, queue.WithDeadLetter(dl))
+	require.NoError(t, err)
+	defer q.Close()
+
+	_, err = q.Enqueue([]byte(`payload`), queue.Policy{Attempts: 2})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return q.Len() == 0 }, time.Second, time.Millisecond)
+
+	letters, err := dl.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, []byte(`payload`), letters[0].Payload)
+	assert.Len(t, letters[0].Attempts, 2, `Must hold the full attempt history`)
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+
+	n, err := retry.Redrive(context.Background(), dl, func(_ context.Context, l retry.Letter) error {
+		_, err := q.Enqueue(l.Payload, queue.Policy{Attempts: 1})
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Eventually(t, func() bool { return q.Len() == 0 }, time.Second, time.Millisecond)
+	letters, err = dl.List(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, letters, `Redriven job must succeed`)
+}
+
+func TestQueueSkipsRetryerDeadLetter(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dl       = retry.NewMemoryDeadLetter()
+		inner    = retry.NewMemoryDeadLetter()
+		nested   = retry.Must(retry.WithDeadLetter(inner))
+		attempts int64
+	)
+	q, err := queue.Open(journalPath(t), retry.Must(retry.WithDeadLetter(dl)), func(ctx context.Context, _ []byte) error {
+		if atomic.AddInt64(&attempts, 1) < 3 {
+			return nested.DoWithContext(ctx, 1, func() error { return errors.New(`discard`) })
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	defer q.Close()
+
+	_, err = q.Enqueue([]byte(`payload`), queue.Policy{Attempts: 3})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return q.Len() == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, int64(3), atomic.LoadInt64(&attempts))
+
+	letters, err := dl.List(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, letters, `Attempts of a job must not be written to the dead letter of the retryer`)
+
+	letters, err = inner.List(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, letters, 2, `Retryers used by the handler must still write to their dead letter`)
+}
@@ -1,8 +1,10 @@
 package retry_test
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -37,3 +39,34 @@ func BenchmarkRetryWithError_FiveAttemptsNoDelays(b *testing.B) {
 	}
 	assert.Error(b, err, "Must not error during successful operation")
 }
+
+func benchmarkAsync(b *testing.B, opts ...retry.Option) {
+	rt, err := retry.New(append(opts, retry.WithFixedDelay(time.Millisecond))...)
+	require.NoError(b, err, "Must have a valid retryer")
+	b.ReportAllocs()
+
+	futures := make([]*retry.Future, 1000)
+	for i := 0; i < b.N; i++ {
+		for j := range futures {
+			futures[j] = retry.DoAsync(context.Background(), rt, 3, func(context.Context) error {
+				return errors.New("boom")
+			})
+		}
+		for _, fu := range futures {
+			err = fu.Wait(context.Background())
+		}
+	}
+	assert.Error(b, err, "Must error once attempts are exceeded")
+}
+
+func BenchmarkAsyncGoroutinePerRetry_ThousandPending(b *testing.B) {
+	benchmarkAsync(b)
+}
+
+func BenchmarkAsyncScheduler_ThousandPending(b *testing.B) {
+	s, err := retry.NewScheduler(8)
+	require.NoError(b, err, "Must have a valid scheduler")
+	defer s.Stop()
+
+	benchmarkAsync(b, retry.WithScheduler(s))
+}
@@ -3,10 +3,38 @@ package retry
 import (
 	"context"
 	"errors"
+	"math"
+	"sync"
+	"time"
 )
 
 type retry struct {
-	actions []func(remaining, limit int)
+	// delays are summed together after each failed attempt
+	// to determine how long to wait before the next attempt.
+	delays []func(remaining, limit int) time.Duration
+	// maxDelay caps the sum of the delays when positive.
+	maxDelay time.Duration
+	// observers are notified of the result of every attempt.
+	observers []func(err error)
+	// permits are checked before each attempt is started, and any error
+	// returned stops the remaining attempts. The returned refund, when not nil,
+	// gives back what was taken if the attempt is never started.
+	permits []func(attempt int, last error) (refund func(), err error)
+	// interceptors wrap each attempt, the first interceptor
+	// being the outer most call.
+	interceptors []func(ctx context.Context, f func() error) error
+	// aborts match any errors that should not be retried
+	// in addition to those marked by AbortedRetries.
+	aborts []func(err error) bool
+
+	hedges     int
+	hedgeDelay time.Duration
+	fallback   func(ctx context.Context, err error) error
+	scheduler  *Scheduler
+	deadLetter DeadLetter
+
+	flightsMu sync.Mutex
+	flights   map[string]*flight
 }
 
 var _ Retryer = (*retry)(nil)
@@ -35,50 +63,272 @@ func Must(opts ...Option) Retryer {
 }
 
 func (r *retry) Do(limit int, f func() error) error {
-	return r.do(context.Background(), limit, f)
+	return r.DoWithContext(context.Background(), limit, f)
 }
 
 func (r *retry) DoWithContext(ctx context.Context, limit int, f func() error) error {
+	if f == nil {
+		return errors.New(`invalid function provided`)
+	}
+	return r.do(ctx, limit, func(context.Context) error {
+		return f()
+	})
+}
+
+// DoWithContextFunc extends the DoWithContext method of the Retryer by passing each attempt
+// a context that is cancelled once the attempts are no longer needed,
+// such as the hedged attempts that were beaten by another.
+// A Retryer that does not implement DoWithContextFunc passes ctx to every attempt.
+func DoWithContextFunc(ctx context.Context, r Retryer, limit int, f func(ctx context.Context) error) error {
+	if r == nil {
+		return errors.New(`retryer is nil`)
+	}
+	if rf, ok := r.(interface {
+		DoWithContextFunc(ctx context.Context, limit int, f func(ctx context.Context) error) error
+	}); ok {
+		return rf.DoWithContextFunc(ctx, limit, f)
+	}
+	if f == nil {
+		return errors.New(`invalid function provided`)
+	}
+	return r.DoWithContext(ctx, limit, func() error {
+		return f(ctx)
+	})
+}
+
+func (r *retry) DoWithContextFunc(ctx context.Context, limit int, f func(ctx context.Context) error) error {
 	return r.do(ctx, limit, f)
 }
 
-func (r *retry) do(ctx context.Context, limit int, f func() error) error {
+func (r *retry) do(ctx context.Context, limit int, f func(ctx context.Context) error) error {
+	if err := validate(ctx, f); err != nil {
+		return err
+	}
+	ctx, f, h := r.record(ctx, f)
+
+	var err error
+	if r.hedges > 0 {
+		err = r.hedged(ctx, limit, f)
+	} else {
+		err = r.attempts(ctx, limit, f)
+	}
+	return r.finish(ctx, err, h)
+}
+
+func validate(ctx context.Context, f func(ctx context.Context) error) error {
 	if ctx == nil || ctx.Err() != nil {
 		return errors.New(`invalid context provided`)
 	}
 	if f == nil {
 		return errors.New(`invalid function provided`)
 	}
+	return nil
+}
 
+// finish writes the attempt history to the dead letter and applies the fallback,
+// if they are configured, to the final error of the attempts.
+func (r *retry) finish(ctx context.Context, err error, h *history) error {
+	if err != nil && h != nil && !notReady(err) && !(ctx.Err() != nil && errors.Is(err, ctx.Err())) {
+		// Write errors are not returned, so that the caller
+		// always receives the error from the attempts made
+		_ = r.deadLetter.Put(ctx, h.letter(ctx, err))
+	}
+	if err != nil && r.fallback != nil {
+		if ferr := r.fallback(ctx, err); ferr != nil {
+			return ferr
+		}
+		return FellBack(err)
+	}
+	return err
+}
+
+func (r *retry) attempts(ctx context.Context, limit int, f func(ctx context.Context) error) error {
 	// Since limit is not being check if negative, the default assumes all
 	// avaliable attempts have been exceeded
 	err := errors.New(`exceeded allowed attempts`)
 	// It is permissable to cache the channel returned here in order to avoid the locking call
 	// within the Done method.
 	done := ctx.Done()
+	// refund gives back the permit of a retry that was not started
+	refund := func() {}
 	for rem := limit; rem > 0; rem-- {
 		select {
 		case <-done:
 			// Context has be finalised, need to exit
+			refund()
 			return ctx.Err()
 		default:
 			// Avoid indefinate waiting on context to finish
 		}
 
-		if err = f(); err == nil {
+		// Retries are permitted before waiting for the delay,
+		// so only the first attempt is permitted here
+		if rem == limit {
+			if _, perr := r.permit(ctx, 1, err); perr != nil {
+				return perr
+			}
+		}
+		// The permit is used once the attempt is made
+		refund = func() {}
+		if err = r.attempt(ctx, f); err == nil {
 			return nil
 		}
-
 		// Check if err is marked as an abort error
 		// an exit from there
-		if HasAborted(err) {
-			return err
+		if aerr := r.abort(err); aerr != nil {
+			return aerr
 		}
 
-		for _, a := range r.actions {
-			a(rem, limit)
+		// No need to wait once there are no attempts remaining
+		if rem > 1 {
+			// A retry that is not permitted fails straight away
+			// instead of after waiting for the delay
+			var perr error
+			if refund, perr = r.permit(ctx, limit-rem+2, err); perr != nil {
+				return perr
+			}
+			if !sleep(ctx, r.backoff(rem, limit)) {
+				refund()
+				return ctx.Err()
+			}
 		}
 	}
 	// Returns the last error recorded
 	return ExceededRetries(err)
 }
+
+// permitKey holds a permit that only applies to the call made with the context,
+// such as the attempts shared by DoAll.
+type permitKey struct{}
+
+type permitFunc func(attempt int, last error) error
+
+// withPermit returns a context that checks the permit before each attempt of the call made with it,
+// the context passed to the attempts does not hold it so calls made within an attempt are not limited.
+func withPermit(ctx context.Context, p permitFunc) context.Context {
+	return context.WithValue(ctx, permitKey{}, p)
+}
+
+// permit checks that the attempt is allowed to start, returning the error that stops
+// any further attempts if it is not. The returned refund must be called if the
+// permitted attempt is not started, such as the context being done while waiting for it.
+func (r *retry) permit(ctx context.Context, attempt int, last error) (func(), error) {
+	var refunds []func()
+	refund := func() {
+		for _, f := range refunds {
+			f()
+		}
+	}
+	if p, _ := ctx.Value(permitKey{}).(permitFunc); p != nil {
+		if err := p(attempt, last); err != nil {
+			return refund, err
+		}
+	}
+	if attempt > 1 && notReady(last) {
+		// Checking again is not a retry of a failed attempt
+		return refund, nil
+	}
+	for _, p := range r.permits {
+		f, err := p(attempt, last)
+		if err != nil {
+			// Permits already taken are not used by an attempt that was not permitted
+			refund()
+			return func() {}, err
+		}
+		if f != nil {
+			refunds = append(refunds, f)
+		}
+	}
+	return refund, nil
+}
+
+// attempt makes a single attempt through all the configured interceptors
+// and notifies the observers of the result.
+func (r *retry) attempt(ctx context.Context, f func(ctx context.Context) error) error {
+	inner := ctx
+	if p, _ := ctx.Value(permitKey{}).(permitFunc); p != nil {
+		inner = context.WithValue(ctx, permitKey{}, permitFunc(nil))
+	}
+	call := func() error {
+		return f(inner)
+	}
+	for i := len(r.interceptors) - 1; i >= 0; i-- {
+		next, in := call, r.interceptors[i]
+		call = func() error {
+			return in(ctx, next)
+		}
+	}
+	err := call()
+	if abandoned(ctx, err) || notReady(err) {
+		return err
+	}
+	for _, o := range r.observers {
+		o(err)
+	}
+	return err
+}
+
+// abort returns the error that stops any further attempts,
+// or nil if err is allowed to be retried.
+func (r *retry) abort(err error) error {
+	if HasAborted(err) {
+		return err
+	}
+	for _, match := range r.aborts {
+		if match(err) {
+			return AbortedRetries(err)
+		}
+	}
+	return nil
+}
+
+// Backoff returns how long the Retryer waits after the failed attempt, out of limit,
+// before making the next attempt. This allows attempts to be made outside of
+// the Do methods, such as from a persisted queue, with the same delays.
+// A Retryer that does not implement Backoff is treated as having no delays.
+func Backoff(r Retryer, attempt, limit int) time.Duration {
+	if rb, ok := r.(interface {
+		Backoff(attempt, limit int) time.Duration
+	}); ok {
+		return rb.Backoff(attempt, limit)
+	}
+	return 0
+}
+
+func (r *retry) Backoff(attempt, limit int) time.Duration {
+	return r.backoff(limit-attempt+1, limit)
+}
+
+// backoff returns how long to wait after a failed attempt.
+func (r *retry) backoff(remaining, limit int) time.Duration {
+	var wait time.Duration
+	for _, d := range r.delays {
+		// Saturates rather than overflowing, since a delay
+		// such as a geometric backoff can grow up to the max duration
+		if next := d(remaining, limit); wait > math.MaxInt64-next {
+			wait = math.MaxInt64
+		} else {
+			wait += next
+		}
+	}
+	if r.maxDelay > 0 && wait > r.maxDelay {
+		wait = r.maxDelay
+	}
+	return wait
+}
+
+// sleep waits for the duration, returning early
+// with false if the context is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -109,6 +109,14 @@ func TestInvalidOptions(t *testing.T) {
 		retry.WithExponentialBackoff(-time.Second, 1.0),
 		retry.WithExponentialBackoff(time.Second, 0.0),
 		retry.WithExponentialBackoff(0, 1.0),
+		retry.WithAbortOn(nil),
+		retry.WithFallback(nil),
+		retry.WithMaxDelay(0),
+		retry.WithGeometricBackoff(0, 2.0),
+		retry.WithGeometricBackoff(time.Second, 0.5),
+		retry.WithFullJitterBackoff(0, 2.0, time.Second),
+		retry.WithFullJitterBackoff(time.Millisecond, 0, time.Second),
+		retry.WithFullJitterBackoff(time.Millisecond, 2.0, 0),
 	}
 
 	for _, opt := range invalid {
@@ -159,3 +167,157 @@ func TestWithAppliedOptions(t *testing.T) {
 		assert.Equal(t, 6, called, `Must have used all allowed attempts`)
 	}
 }
+
+func TestAbortOnMatchedErrors(t *testing.T) {
+	t.Parallel()
+
+	permanent := errors.New(`permanent`)
+	r := retry.Must(retry.WithAbortOn(func(err error) bool {
+		return errors.Is(err, permanent)
+	}))
+
+	called := 0
+	err := r.Do(4, func() error {
+		called++
+		return permanent
+	})
+	assert.True(t, retry.HasAborted(err), `Matched errors must abort retries`)
+	assert.True(t, errors.Is(err, permanent))
+	assert.Equal(t, 1, called)
+
+	called = 0
+	err = r.Do(4, func() error {
+		called++
+		return errors.New(`transient`)
+	})
+	assert.True(t, retry.HasExceeded(err), `Unmatched errors must be retried`)
+	assert.Equal(t, 4, called)
+}
+
+func TestFallbackOnExhaustion(t *testing.T) {
+	t.Parallel()
+
+	var (
+		cached = `cached`
+		value  string
+		final  error
+	)
+	r := retry.Must(retry.WithFallback(func(_ context.Context, err error) error {
+		final = err
+		value = cached
+		return nil
+	}))
+
+	err := r.Do(3, func() error {
+		return errors.New(`discard`)
+	})
+	assert.True(t, retry.HasFallenBack(err), `Must report the result came from the fallback`)
+	assert.True(t, retry.HasExceeded(err), `Must keep the final error`)
+	assert.True(t, retry.HasExceeded(final), `Fallback must receive the final error`)
+	assert.Equal(t, cached, value)
+
+	final = nil
+	assert.NoError(t, r.Do(3, func() error { return nil }))
+	assert.Nil(t, final, `Fallback must not run when attempts succeed`)
+
+	failed := errors.New(`no cache`)
+	err = retry.Must(retry.WithFallback(func(_ context.Context, err error) error {
+		return failed
+	})).Do(1, func() error {
+		return retry.AbortedRetries(errors.New(`doom`))
+	})
+	assert.Equal(t, failed, err, `Must return the error of a failed fallback`)
+
+	_, err = retry.New(
+		retry.WithFallback(func(context.Context, error) error { return nil }),
+		retry.WithFallback(func(context.Context, error) error { return nil }),
+	)
+	assert.Error(t, err, `Must not allow more than one fallback`)
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, time.Duration(0), retry.Backoff(retry.Must(), 1, 3), `Must not wait without any delays`)
+
+	r := retry.Must(retry.WithFixedDelay(time.Second), retry.WithExponentialBackoff(time.Millisecond, 2.0))
+	assert.Equal(t, time.Second, retry.Backoff(r, 1, 3))
+	assert.Equal(t, time.Second+2*time.Millisecond, retry.Backoff(r, 2, 3))
+
+	r = retry.Must(retry.WithExponentialBackoff(time.Second, 2.0), retry.WithMaxDelay(3*time.Second))
+	assert.Equal(t, 2*time.Second, retry.Backoff(r, 2, 5))
+	assert.Equal(t, 3*time.Second, retry.Backoff(r, 4, 5), `Delay must be capped by the max delay`)
+}
+
+func TestGeometricBackoff(t *testing.T) {
+	t.Parallel()
+
+	r := retry.Must(retry.WithGeometricBackoff(200*time.Millisecond, 1.5))
+	for attempt, expect := range []time.Duration{
+		200 * time.Millisecond,
+		300 * time.Millisecond,
+		450 * time.Millisecond,
+		675 * time.Millisecond,
+	} {
+		assert.Equal(t, expect, retry.Backoff(r, attempt+1, 5), `Delay after attempt %d must grow geometrically`, attempt+1)
+	}
+
+	r = retry.Must(retry.WithGeometricBackoff(time.Second, 10), retry.WithMaxDelay(time.Minute))
+	assert.Equal(t, time.Minute, retry.Backoff(r, 100, 101), `Delay must not overflow before being capped`)
+
+	r = retry.Must(retry.WithGeometricBackoff(time.Second, 10), retry.WithJitter(time.Second), retry.WithMaxDelay(time.Minute))
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, time.Minute, retry.Backoff(r, 30, 40), `Delays must not overflow when added together`)
+	}
+}
+
+func TestFullJitterBackoff(t *testing.T) {
+	t.Parallel()
+
+	r := retry.Must(retry.WithFullJitterBackoff(100*time.Millisecond, 2.0, 300*time.Millisecond))
+	for attempt, ceiling := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 300 * time.Millisecond,
+		4: 300 * time.Millisecond,
+	} {
+		for i := 0; i < 100; i++ {
+			d := retry.Backoff(r, attempt, 5)
+			assert.GreaterOrEqual(t, int64(d), int64(0))
+			assert.Less(t, int64(d), int64(ceiling), `Delay after attempt %d must be less than %v`, attempt, ceiling)
+		}
+	}
+}
+
+// baseRetryer only provides the methods of the Retryer interface.
+type baseRetryer struct {
+	retry.Retryer
+}
+
+func TestPackageFunctionsWithBaseRetryer(t *testing.T) {
+	t.Parallel()
+
+	r := baseRetryer{retry.Must(retry.WithFixedDelay(time.Millisecond))}
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, `value`)
+	attempts := 0
+	err := retry.DoWithContextFunc(ctx, r, 3, func(ctx context.Context) error {
+		assert.Equal(t, `value`, ctx.Value(key{}), `Must pass the context to each attempt`)
+		if attempts++; attempts < 2 {
+			return errors.New(`discard`)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+
+	assert.Error(t, retry.DoWithContextFunc(ctx, nil, 1, func(context.Context) error { return nil }))
+	assert.Error(t, retry.DoWithContextFunc(ctx, r, 1, nil))
+	assert.Equal(t, time.Duration(0), retry.Backoff(r, 1, 3), `Must not wait without a known backoff`)
+	assert.NoError(t, retry.DoKeyed(ctx, r, `key`, 1, func(context.Context) error { return nil }))
+
+	fu := retry.DoAsync(ctx, r, 1, func(context.Context) error { return errors.New(`discard`) })
+	assert.True(t, retry.HasExceeded(fu.Wait(context.Background())))
+	assert.Equal(t, 1, fu.Progress().Attempts)
+}
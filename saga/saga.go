@@ -0,0 +1,139 @@
+// Package saga orchestrates multi step operations where each step is retried,
+// and the steps that have completed are compensated in reverse order
+// once a later step has failed.
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+// Policy controls how an action or compensation is retried.
+type Policy struct {
+	// Retryer is used to make the attempts, without any delays if not set.
+	Retryer retry.Retryer
+	// Attempts is the limit of attempts made, a single attempt if not set.
+	Attempts int
+}
+
+func (p Policy) do(ctx context.Context, f func(ctx context.Context) error) (int, error) {
+	r, limit := p.Retryer, p.Attempts
+	if r == nil {
+		r = retry.Must()
+	}
+	if limit == 0 {
+		limit = 1
+	}
+	// Counted atomically since a hedging Retryer makes attempts concurrently
+	var attempts int64
+	err := retry.DoWithContextFunc(ctx, r, limit, func(ctx context.Context) error {
+		atomic.AddInt64(&attempts, 1)
+		return f(ctx)
+	})
+	return int(attempts), err
+}
+
+// Step is a single step of the saga.
+type Step struct {
+	Name string
+	// Action performs the step.
+	Action func(ctx context.Context) error
+	// Compensate optionally undoes the action once a later step has failed.
+	Compensate func(ctx context.Context) error
+
+	ActionPolicy     Policy
+	CompensatePolicy Policy
+}
+
+// StepReport records what happened to a step while running the saga.
+type StepReport struct {
+	Name               string
+	Completed          bool
+	ActionErr          error
+	ActionAttempts     int
+	Compensated        bool
+	CompensateErr      error
+	CompensateAttempts int
+}
+
+// Report is the structured result of running the saga,
+// holding a report for every step that was started.
+type Report struct {
+	Steps []StepReport
+	// Failed is the name of the step that failed, empty when the saga completed.
+	Failed string
+}
+
+// Saga runs the steps in order.
+type Saga struct {
+	steps []Step
+}
+
+// New validates the steps and creates a saga that runs them in the order passed.
+func New(steps ...Step) (*Saga, error) {
+	if len(steps) == 0 {
+		return nil, errors.New(`saga must have at least one step`)
+	}
+	names := make(map[string]struct{}, len(steps))
+	for _, s := range steps {
+		if s.Name == "" {
+			return nil, errors.New(`step name must not be empty`)
+		}
+		if _, exist := names[s.Name]; exist {
+			return nil, fmt.Errorf("step %q already exists", s.Name)
+		}
+		names[s.Name] = struct{}{}
+		if s.Action == nil {
+			return nil, fmt.Errorf("step %q has no action", s.Name)
+		}
+		for _, p := range []Policy{s.ActionPolicy, s.CompensatePolicy} {
+			if p.Attempts < 0 {
+				return nil, fmt.Errorf("step %q attempts must not be negative", s.Name)
+			}
+		}
+	}
+	return &Saga{steps: steps}, nil
+}
+
+// Run performs each step in order, retrying each action with its policy.
+// Once a step fails, the compensations of every completed step are run in reverse order
+// with their own policies. Compensations are run using a background context, so they
+// are still run if the saga failed due to ctx being done.
+// The returned error wraps the error of the failed step, and reports any compensations that failed.
+func (s *Saga) Run(ctx context.Context) (Report, error) {
+	var report Report
+	for _, step := range s.steps {
+		sr := StepReport{Name: step.Name}
+		sr.ActionAttempts, sr.ActionErr = step.ActionPolicy.do(ctx, step.Action)
+		sr.Completed = sr.ActionErr == nil
+		report.Steps = append(report.Steps, sr)
+		if !sr.Completed {
+			report.Failed = step.Name
+			break
+		}
+	}
+	if report.Failed == "" {
+		return report, nil
+	}
+
+	failed := report.Steps[len(report.Steps)-1]
+	compensations := 0
+	for i := len(report.Steps) - 2; i >= 0; i-- {
+		step, sr := s.steps[i], &report.Steps[i]
+		if step.Compensate == nil {
+			continue
+		}
+		sr.CompensateAttempts, sr.CompensateErr = step.CompensatePolicy.do(context.Background(), step.Compensate)
+		if sr.Compensated = sr.CompensateErr == nil; !sr.Compensated {
+			compensations++
+		}
+	}
+	if compensations > 0 {
+		return report, fmt.Errorf("saga step %q failed and %d compensations failed: %w", failed.Name, compensations, failed.ActionErr)
+	}
+	return report, fmt.Errorf("saga step %q failed: %w", failed.Name, failed.ActionErr)
+}
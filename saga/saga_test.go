@@ -0,0 +1,113 @@
+package saga_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+	"github.com/MovieStoreGuy/retry/saga"
+)
+
+func TestInvalidSaga(t *testing.T) {
+	t.Parallel()
+
+	action := func(context.Context) error { return nil }
+	invalid := [][]saga.Step{
+		{},
+		{{Action: action}},
+		{{Name: `reserve`}},
+		{{Name: `reserve`, Action: action}, {Name: `reserve`, Action: action}},
+		{{Name: `reserve`, Action: action, ActionPolicy: saga.Policy{Attempts: -1}}},
+	}
+	for _, steps := range invalid {
+		_, err := saga.New(steps...)
+		assert.Error(t, err)
+	}
+}
+
+func TestSagaCompletes(t *testing.T) {
+	t.Parallel()
+
+	var order []string
+	step := func(name string) saga.Step {
+		called := 0
+		return saga.Step{
+			Name: name,
+			Action: func(context.Context) error {
+				if called++; called < 2 {
+					return errors.New(`transient`)
+				}
+				order = append(order, name)
+				return nil
+			},
+			ActionPolicy: saga.Policy{Retryer: retry.Must(), Attempts: 3},
+		}
+	}
+
+	s, err := saga.New(step(`reserve`), step(`charge`), step(`ship`))
+	require.NoError(t, err)
+
+	report, err := s.Run(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, report.Failed)
+	assert.Equal(t, []string{`reserve`, `charge`, `ship`}, order)
+	for _, sr := range report.Steps {
+		assert.True(t, sr.Completed)
+		assert.Equal(t, 2, sr.ActionAttempts, `Each step must be retried`)
+	}
+}
+
+func TestSagaCompensates(t *testing.T) {
+	t.Parallel()
+
+	var undone []string
+	compensate := func(name string, failures int) func(context.Context) error {
+		called := 0
+		return func(context.Context) error {
+			if called++; called <= failures {
+				return errors.New(`transient`)
+			}
+			undone = append(undone, name)
+			return nil
+		}
+	}
+	ok := func(context.Context) error { return nil }
+	permanent := errors.New(`card declined`)
+
+	s, err := saga.New(
+		saga.Step{Name: `reserve`, Action: ok, Compensate: compensate(`reserve`, 1), CompensatePolicy: saga.Policy{Attempts: 2}},
+		saga.Step{Name: `notify`, Action: ok},
+		saga.Step{Name: `hold`, Action: ok, Compensate: compensate(`hold`, 0)},
+		saga.Step{Name: `charge`, Action: func(context.Context) error {
+			return retry.AbortedRetries(permanent)
+		}, ActionPolicy: saga.Policy{Attempts: 5}},
+		saga.Step{Name: `ship`, Action: ok},
+	)
+	require.NoError(t, err)
+
+	report, err := s.Run(context.Background())
+	assert.True(t, errors.Is(err, permanent), `Must wrap the error of the failed step`)
+	assert.Contains(t, err.Error(), `saga step "charge" failed`)
+	assert.Equal(t, `charge`, report.Failed)
+	assert.Equal(t, []string{`hold`, `reserve`}, undone, `Must compensate in reverse order`)
+
+	require.Len(t, report.Steps, 4, `Steps after the failure must not be started`)
+	assert.Equal(t, saga.StepReport{Name: `reserve`, Completed: true, ActionAttempts: 1, Compensated: true, CompensateAttempts: 2}, report.Steps[0])
+	assert.Equal(t, saga.StepReport{Name: `notify`, Completed: true, ActionAttempts: 1}, report.Steps[1])
+	assert.True(t, report.Steps[2].Compensated)
+	assert.Equal(t, 1, report.Steps[3].ActionAttempts, `Permanent failures must not be retried`)
+
+	s, err = saga.New(
+		saga.Step{Name: `reserve`, Action: ok, Compensate: compensate(`reserve`, 5)},
+		saga.Step{Name: `charge`, Action: func(context.Context) error { return permanent }},
+	)
+	require.NoError(t, err)
+	report, err = s.Run(context.Background())
+	assert.Contains(t, err.Error(), `1 compensations failed`)
+	assert.False(t, report.Steps[0].Compensated)
+	assert.Error(t, report.Steps[0].CompensateErr)
+}
@@ -0,0 +1,311 @@
+package retry
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrSchedulerStopped is returned by any attempts that
+// were still pending when the Scheduler was stopped.
+var ErrSchedulerStopped = errors.New(`scheduler has been stopped`)
+
+// task holds the state of attempts made through a Scheduler
+// so that no goroutine is held while waiting for the next attempt.
+type task struct {
+	r       *retry
+	ctx     context.Context
+	f       func(ctx context.Context) error
+	limit   int
+	attempt int
+	err     error
+	future  *Future
+	history *history
+	// refund gives back the permit of the next attempt if it is never started.
+	refund func()
+
+	at    time.Time
+	index int
+}
+
+// step makes the next attempt, returning how long to wait before the next attempt
+// and true if there are further attempts to be made.
+func (t *task) step() (time.Duration, bool) {
+	if t.ctx.Err() != nil {
+		t.finish(t.ctx.Err())
+		return 0, false
+	}
+	if t.attempt >= t.limit {
+		// Since limit is not being check if negative, the default assumes all
+		// avaliable attempts have been exceeded
+		t.finish(ExceededRetries(errors.New(`exceeded allowed attempts`)))
+		return 0, false
+	}
+	// Retries are permitted before waiting for the delay,
+	// so only the first attempt is permitted here
+	if t.attempt == 0 {
+		if _, err := t.r.permit(t.ctx, 1, t.err); err != nil {
+			t.finish(err)
+			return 0, false
+		}
+	}
+
+	t.refund = nil
+	t.attempt++
+	if t.err = t.r.attempt(t.ctx, t.f); t.err == nil {
+		t.future.complete(nil)
+		return 0, false
+	}
+	if err := t.r.abort(t.err); err != nil {
+		t.finish(err)
+		return 0, false
+	}
+	if t.attempt >= t.limit {
+		t.finish(ExceededRetries(t.err))
+		return 0, false
+	}
+	refund, err := t.r.permit(t.ctx, t.attempt+1, t.err)
+	if err != nil {
+		t.finish(err)
+		return 0, false
+	}
+	t.refund = refund
+	return t.r.backoff(t.limit-t.attempt+1, t.limit), true
+}
+
+// cancel gives back the permit of the next attempt, as it is not going to be started.
+func (t *task) cancel() {
+	if t.refund != nil {
+		t.refund()
+		t.refund = nil
+	}
+}
+
+func (t *task) finish(err error) {
+	t.cancel()
+	t.future.complete(t.r.finish(t.ctx, err, t.history))
+}
+
+type pending []*task
+
+func (p pending) Len() int           { return len(p) }
+func (p pending) Less(i, j int) bool { return p[i].at.Before(p[j].at) }
+func (p pending) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+	p[i].index, p[j].index = i, j
+}
+
+func (p *pending) Push(v interface{}) {
+	t := v.(*task)
+	t.index = len(*p)
+	*p = append(*p, t)
+}
+
+func (p *pending) Pop() interface{} {
+	old := *p
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.index = -1
+	*p = old[:len(old)-1]
+	return t
+}
+
+// Scheduler runs the attempts of many DoAsync calls on a bounded pool of workers,
+// using a single timer heap to wake any that are waiting for their next attempt
+// instead of holding a sleeping goroutine for each of them.
+// Cancelling a Future, or its parent context being done, removes it from the
+// Scheduler straight away rather than once its next attempt is due.
+type Scheduler struct {
+	mu      sync.Mutex
+	pending pending
+	stopped bool
+
+	wake chan struct{}
+	work chan *task
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewScheduler creates a running scheduler that makes attempts using the number of workers.
+func NewScheduler(workers int) (*Scheduler, error) {
+	if workers < 1 {
+		return nil, errors.New(`workers must be positive`)
+	}
+	s := &Scheduler{
+		wake: make(chan struct{}, 1),
+		work: make(chan *task),
+		quit: make(chan struct{}),
+	}
+	s.wg.Add(workers + 1)
+	for i := 0; i < workers; i++ {
+		go s.worker()
+	}
+	go s.run()
+	return s, nil
+}
+
+// Stop stops the scheduler after waiting for any running attempts,
+// completing all pending attempts with ErrSchedulerStopped.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.stopped = true
+	close(s.quit)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.pending.Len() > 0 {
+		t := heap.Pop(&s.pending).(*task)
+		t.cancel()
+		t.future.complete(ErrSchedulerStopped)
+	}
+}
+
+func (s *Scheduler) submit(t *task) {
+	t.future.stop = func() {
+		s.remove(t)
+	}
+	// The context of the task is cancelled once its future completes,
+	// so the watch never outlives the task.
+	go func() {
+		select {
+		case <-t.ctx.Done():
+			s.remove(t)
+		case <-s.quit:
+		}
+	}()
+	s.schedule(t, 0)
+}
+
+func (s *Scheduler) schedule(t *task, wait time.Duration) {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		t.future.complete(ErrSchedulerStopped)
+		return
+	}
+	// Checked while holding the lock since remove is not able to find a task that
+	// was cancelled while its attempt was running, and so would otherwise wait for its delay.
+	if t.ctx.Err() != nil {
+		s.mu.Unlock()
+		t.finish(t.ctx.Err())
+		return
+	}
+	t.at = time.Now().Add(wait)
+	heap.Push(&s.pending, t)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+		// Timer loop has already been woken
+	}
+}
+
+// remove takes the task out of the heap if it is waiting for its next attempt,
+// tasks that are currently running notice the cancelled context when they are rescheduled.
+func (s *Scheduler) remove(t *task) {
+	s.mu.Lock()
+	removed := t.index >= 0 && t.index < s.pending.Len() && s.pending[t.index] == t
+	if removed {
+		heap.Remove(&s.pending, t.index)
+	}
+	s.mu.Unlock()
+
+	if removed {
+		t.finish(t.ctx.Err())
+	}
+}
+
+func (s *Scheduler) worker() {
+	defer s.wg.Done()
+	for {
+		select {
+		case t := <-s.work:
+			if wait, more := t.step(); more {
+				s.schedule(t, wait)
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) run() {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		var ready []*task
+		wait := time.Hour
+
+		s.mu.Lock()
+		now := time.Now()
+		for s.pending.Len() > 0 {
+			if next := s.pending[0].at.Sub(now); next > 0 {
+				wait = next
+				break
+			}
+			ready = append(ready, heap.Pop(&s.pending).(*task))
+		}
+		s.mu.Unlock()
+
+		for i, t := range ready {
+			select {
+			case s.work <- t:
+			case <-s.quit:
+				// Return the tasks that were not started,
+				// so they are completed by Stop
+				s.mu.Lock()
+				for _, t := range ready[i:] {
+					heap.Push(&s.pending, t)
+				}
+				s.mu.Unlock()
+				return
+			}
+		}
+		if len(ready) > 0 {
+			// Time has passed while handing out work,
+			// so check the heap again before waiting
+			continue
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-timer.C:
+		case <-s.wake:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// WithScheduler runs the attempts made by DoAsync on the scheduler
+// rather than in a goroutine of their own.
+// Hedged attempts are not supported by the scheduler and continue to use a goroutine.
+func WithScheduler(s *Scheduler) Option {
+	return func(r *retry) error {
+		if s == nil {
+			return errors.New(`scheduler must not be nil`)
+		}
+		r.scheduler = s
+		return nil
+	}
+}
@@ -0,0 +1,160 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+func TestInvalidScheduler(t *testing.T) {
+	t.Parallel()
+
+	_, err := retry.NewScheduler(0)
+	assert.Error(t, err, `Must not allow non positive workers`)
+
+	_, err = retry.New(retry.WithScheduler(nil))
+	assert.Error(t, err, `Must not allow a nil scheduler`)
+}
+
+func TestSchedulerRunsAttempts(t *testing.T) {
+	t.Parallel()
+
+	s, err := retry.NewScheduler(4)
+	require.NoError(t, err)
+	defer s.Stop()
+
+	r := retry.Must(retry.WithScheduler(s), retry.WithFixedDelay(time.Millisecond))
+
+	var futures []*retry.Future
+	var called int64
+	for i := 0; i < 100; i++ {
+		futures = append(futures, retry.DoAsync(context.Background(), r, 3, func(context.Context) error {
+			if atomic.AddInt64(&called, 1)%3 == 0 {
+				return nil
+			}
+			return errors.New(`discard`)
+		}))
+	}
+	for _, fu := range futures {
+		err := fu.Wait(context.Background())
+		assert.True(t, err == nil || retry.HasExceeded(err), `Must complete with the result of the attempts`)
+		assert.LessOrEqual(t, fu.Progress().Attempts, 3)
+	}
+
+	fu := retry.DoAsync(context.Background(), r, 2, func(context.Context) error {
+		return retry.AbortedRetries(errors.New(`doom`))
+	})
+	assert.True(t, retry.HasAborted(fu.Wait(context.Background())))
+	assert.Equal(t, 1, fu.Progress().Attempts)
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	t.Parallel()
+
+	s, err := retry.NewScheduler(1)
+	require.NoError(t, err)
+	defer s.Stop()
+
+	attempted := make(chan struct{})
+	fu := retry.DoAsync(context.Background(), retry.Must(retry.WithScheduler(s), retry.WithFixedDelay(time.Hour)), 2, func(context.Context) error {
+		close(attempted)
+		return errors.New(`discard`)
+	})
+	<-attempted
+
+	fu.Cancel()
+	select {
+	case <-fu.Done():
+	case <-time.After(time.Second):
+		assert.Fail(t, `Cancel must remove pending attempts from the scheduler`)
+	}
+	assert.Equal(t, context.Canceled, fu.Wait(context.Background()))
+}
+
+func TestSchedulerParentCancel(t *testing.T) {
+	t.Parallel()
+
+	s, err := retry.NewScheduler(1)
+	require.NoError(t, err)
+	defer s.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	attempted := make(chan struct{})
+	fu := retry.DoAsync(ctx, retry.Must(retry.WithScheduler(s), retry.WithFixedDelay(time.Hour)), 2, func(context.Context) error {
+		close(attempted)
+		return errors.New(`discard`)
+	})
+	<-attempted
+
+	cancel()
+	select {
+	case <-fu.Done():
+	case <-time.After(time.Second):
+		require.Fail(t, `Parent context must remove pending attempts from the scheduler`)
+	}
+	assert.Equal(t, context.Canceled, fu.Wait(context.Background()))
+	assert.Equal(t, 1, fu.Progress().Attempts)
+}
+
+func TestSchedulerCancelDuringAttempt(t *testing.T) {
+	t.Parallel()
+
+	s, err := retry.NewScheduler(1)
+	require.NoError(t, err)
+	defer s.Stop()
+
+	var (
+		attempting = make(chan struct{})
+		cancelled  = make(chan struct{})
+	)
+	fu := retry.DoAsync(context.Background(), retry.Must(retry.WithScheduler(s), retry.WithFixedDelay(time.Hour)), 2, func(context.Context) error {
+		close(attempting)
+		<-cancelled
+		return errors.New(`discard`)
+	})
+	<-attempting
+	fu.Cancel()
+	close(cancelled)
+
+	select {
+	case <-fu.Done():
+	case <-time.After(time.Second):
+		assert.Fail(t, `Cancel during an attempt must not wait for the delay`)
+	}
+	assert.Equal(t, context.Canceled, fu.Wait(context.Background()))
+	assert.Equal(t, 1, fu.Progress().Attempts)
+}
+
+func TestSchedulerStop(t *testing.T) {
+	t.Parallel()
+
+	s, err := retry.NewScheduler(1)
+	require.NoError(t, err)
+
+	r := retry.Must(retry.WithScheduler(s), retry.WithFixedDelay(time.Hour))
+	fu := retry.DoAsync(context.Background(), r, 2, func(context.Context) error {
+		return errors.New(`discard`)
+	})
+	assert.Eventually(t, func() bool { return fu.Progress().Attempts == 1 }, time.Second, time.Millisecond)
+
+	s.Stop()
+	s.Stop()
+	select {
+	case <-fu.Done():
+	case <-time.After(time.Second):
+		require.Fail(t, `Stop must complete pending attempts`)
+	}
+	assert.Equal(t, retry.ErrSchedulerStopped, fu.Wait(context.Background()))
+
+	fu = retry.DoAsync(context.Background(), r, 1, func(context.Context) error { return nil })
+	assert.Equal(t, retry.ErrSchedulerStopped, fu.Wait(context.Background()), `Stopped scheduler must not accept attempts`)
+}
@@ -0,0 +1,143 @@
+package retry
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const stormBuckets = 10
+
+// StormState is reported by the StormDetector each time
+// it starts or stops suppressing retries.
+type StormState struct {
+	Name       string
+	Suppressed bool
+	Ratio      float64
+}
+
+type stormBucket struct {
+	start   int64
+	firsts  int
+	retries int
+}
+
+// StormDetector tracks the ratio of retries to first attempts over a sliding window,
+// and suppresses any further retries while the ratio is above the configured threshold.
+// This is intended as an additional layer of defence against retry storms,
+// so a single dependency outage does not get amplified by every caller retrying.
+type StormDetector struct {
+	mu         sync.Mutex
+	name       string
+	threshold  float64
+	width      int64
+	buckets    [stormBuckets]stormBucket
+	suppressed bool
+	notify     func(StormState)
+}
+
+// NewStormDetector creates a detector for the named Retryer that suppresses retries
+// while retries make up more than threshold of the first attempts seen within window.
+// The notify function is optional and is called each time suppression is started or stopped.
+func NewStormDetector(name string, window time.Duration, threshold float64, notify func(StormState)) (*StormDetector, error) {
+	if window < stormBuckets {
+		return nil, errors.New(`window is too small`)
+	}
+	if threshold <= 0 {
+		return nil, errors.New(`threshold must be a positive value`)
+	}
+	return &StormDetector{
+		name:      name,
+		threshold: threshold,
+		width:     int64(window / stormBuckets),
+		notify:    notify,
+	}, nil
+}
+
+// State returns the current state of the detector.
+func (s *StormDetector) State() StormState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return StormState{Name: s.name, Suppressed: s.suppressed, Ratio: s.ratio(time.Now())}
+}
+
+// bucket returns the bucket for the current time,
+// resetting it if it was last used in a previous window.
+func (s *StormDetector) bucket(now time.Time) *stormBucket {
+	start := now.UnixNano() / s.width
+	b := &s.buckets[start%stormBuckets]
+	if b.start != start {
+		*b = stormBucket{start: start}
+	}
+	return b
+}
+
+func (s *StormDetector) ratio(now time.Time) float64 {
+	var firsts, retries int
+	oldest := now.UnixNano()/s.width - stormBuckets
+	for _, b := range s.buckets {
+		if b.start > oldest {
+			firsts += b.firsts
+			retries += b.retries
+		}
+	}
+	if firsts == 0 {
+		return 0
+	}
+	return float64(retries) / float64(firsts)
+}
+
+// allow records the attempt and reports if it is permitted to start,
+// along with a refund that removes a recorded retry if it is never made.
+func (s *StormDetector) allow(attempt int) (bool, func()) {
+	s.mu.Lock()
+	now := time.Now()
+	if attempt == 1 {
+		s.bucket(now).firsts++
+	}
+	ratio := s.ratio(now)
+	allowed := attempt == 1 || ratio <= s.threshold
+	var refund func()
+	if attempt > 1 && allowed {
+		b := s.bucket(now)
+		b.retries++
+		start := b.start
+		refund = func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			// The bucket may have since been reused for a later window
+			if b.start == start && b.retries > 0 {
+				b.retries--
+			}
+		}
+	}
+
+	changed := s.suppressed != (ratio > s.threshold)
+	s.suppressed = ratio > s.threshold
+	state := StormState{Name: s.name, Suppressed: s.suppressed, Ratio: ratio}
+	s.mu.Unlock()
+
+	if changed && s.notify != nil {
+		s.notify(state)
+	}
+	return allowed, refund
+}
+
+// WithStormDetector suppresses retries, returning a suppressed retries error,
+// while the detector has observed too many retries compared to first attempts.
+// A retry that is not made, as the context is done while waiting for it, is not counted.
+func WithStormDetector(s *StormDetector) Option {
+	return func(r *retry) error {
+		if s == nil {
+			return errors.New(`storm detector must not be nil`)
+		}
+		r.permits = append(r.permits, func(attempt int, last error) (func(), error) {
+			allowed, refund := s.allow(attempt)
+			if !allowed {
+				return nil, SuppressedRetries(last)
+			}
+			return refund, nil
+		})
+		return nil
+	}
+}
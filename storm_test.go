@@ -0,0 +1,62 @@
+package retry_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MovieStoreGuy/retry"
+)
+
+func TestInvalidStormDetector(t *testing.T) {
+	t.Parallel()
+
+	_, err := retry.NewStormDetector(`invalid`, 0, 0.1, nil)
+	assert.Error(t, err, `Must not allow an empty window`)
+
+	_, err = retry.NewStormDetector(`invalid`, time.Second, 0, nil)
+	assert.Error(t, err, `Must not allow a non positive threshold`)
+
+	_, err = retry.New(retry.WithStormDetector(nil))
+	assert.Error(t, err, `Must not allow a nil storm detector`)
+}
+
+func TestStormDetectorSuppressesRetries(t *testing.T) {
+	t.Parallel()
+
+	var states []retry.StormState
+	s, err := retry.NewStormDetector(`storm`, time.Minute, 0.5, func(state retry.StormState) {
+		states = append(states, state)
+	})
+	require.NoError(t, err)
+	r := retry.Must(retry.WithStormDetector(s))
+
+	called := 0
+	err = r.Do(4, func() error {
+		called++
+		return errors.New(`discard`)
+	})
+	assert.True(t, retry.HasSuppressed(err), `Retries must be suppressed once above the threshold`)
+	assert.EqualError(t, errors.Unwrap(err), `discard`, `Must wrap the last error`)
+	assert.Equal(t, 2, called)
+	require.Len(t, states, 1)
+	assert.Equal(t, retry.StormState{Name: `storm`, Suppressed: true, Ratio: 1}, states[0])
+	assert.True(t, s.State().Suppressed)
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, r.Do(1, func() error { return nil }))
+	}
+	require.Len(t, states, 2, `Must report once the ratio drops below the threshold`)
+	assert.False(t, states[1].Suppressed)
+
+	called = 0
+	err = r.Do(2, func() error {
+		called++
+		return errors.New(`discard`)
+	})
+	assert.True(t, retry.HasExceeded(err), `Retries must be allowed again`)
+	assert.Equal(t, 2, called)
+}